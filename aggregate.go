@@ -0,0 +1,75 @@
+package emhcasa
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Aggregate is a Gateway merging the readings of several gateways, e.g. for
+// buildings with separate metering points for consumption and PV.
+type Aggregate struct {
+	sources map[string]Gateway
+}
+
+// NewAggregate merges the given gateways, keyed by source name.
+func NewAggregate(sources map[string]Gateway) *Aggregate {
+	return &Aggregate{sources: sources}
+}
+
+// GetReadings implements Gateway. It returns the readings of all sources with
+// their OBIS key prefixed by the source name ("pv/16.7.0"), followed by the sums
+// of power and energy readings across sources under the plain key ("16.7.0").
+// Fails if any source fails.
+func (a *Aggregate) GetReadings() ([]Reading, error) {
+	var res []Reading
+	sums := make(map[string]Reading)
+
+	for _, name := range slices.Sorted(maps.Keys(a.sources)) {
+		readings, err := a.sources[name].GetReadings()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		for _, r := range readings {
+			if isAdditive(r.Unit) {
+				sums[r.OBIS] = addReading(sums[r.OBIS], r)
+			}
+
+			r.OBIS = name + "/" + r.OBIS
+			res = append(res, r)
+		}
+	}
+
+	for _, code := range slices.Sorted(maps.Keys(sums)) {
+		res = append(res, sums[code])
+	}
+
+	return res, nil
+}
+
+// isAdditive reports whether values of the unit can be summed across meters
+func isAdditive(u Unit) bool {
+	switch u {
+	case UnitWatt, UnitVoltAmpere, UnitVar, UnitWattHour, UnitVoltAmpereHour, UnitVarHour:
+		return true
+	}
+	return false
+}
+
+// addReading adds r to the sum, keeping the latest timestamps and worst quality
+func addReading(sum, r Reading) Reading {
+	sum.OBIS, sum.Unit = r.OBIS, r.Unit
+	sum.Value += r.Value
+	sum.ValueMilli += r.ValueMilli
+	sum.Quality = max(sum.Quality, r.Quality)
+
+	if r.Timestamp.After(sum.Timestamp) {
+		sum.Timestamp = r.Timestamp
+	}
+	if r.ReceivedAt.After(sum.ReceivedAt) {
+		sum.ReceivedAt = r.ReceivedAt
+	}
+
+	return sum
+}
@@ -0,0 +1,46 @@
+package emhcasa
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestAggregate tests merging readings of several gateways
+func TestAggregate(t *testing.T) {
+	grid := gatewayFunc(func() ([]Reading, error) {
+		return []Reading{
+			{OBIS: "16.7.0", Value: 1500, ValueMilli: 1500000, Unit: UnitWatt},
+			{OBIS: "32.7.0", Value: 230, ValueMilli: 230000, Unit: UnitVolt},
+		}, nil
+	})
+	pv := gatewayFunc(func() ([]Reading, error) {
+		return []Reading{
+			{OBIS: "16.7.0", Value: -2000, ValueMilli: -2000000, Unit: UnitWatt, Quality: QualityInvalid},
+		}, nil
+	})
+
+	readings, err := NewAggregate(map[string]Gateway{"pv": pv, "grid": grid}).GetReadings()
+	if err != nil {
+		t.Fatalf("GetReadings() error = %v", err)
+	}
+
+	want := []Reading{
+		{OBIS: "grid/16.7.0", Value: 1500, ValueMilli: 1500000, Unit: UnitWatt},
+		{OBIS: "grid/32.7.0", Value: 230, ValueMilli: 230000, Unit: UnitVolt},
+		{OBIS: "pv/16.7.0", Value: -2000, ValueMilli: -2000000, Unit: UnitWatt, Quality: QualityInvalid},
+		{OBIS: "16.7.0", Value: -500, ValueMilli: -500000, Unit: UnitWatt, Quality: QualityInvalid},
+	}
+	if len(readings) != len(want) {
+		t.Fatalf("GetReadings() = %+v, want %+v", readings, want)
+	}
+	for i := range want {
+		if readings[i] != want[i] {
+			t.Errorf("GetReadings()[%d] = %+v, want %+v", i, readings[i], want[i])
+		}
+	}
+
+	failing := gatewayFunc(func() ([]Reading, error) { return nil, errors.New("timeout") })
+	if _, err := NewAggregate(map[string]Gateway{"grid": grid, "pv": failing}).GetReadings(); err == nil {
+		t.Error("GetReadings() expected error for failing source")
+	}
+}
@@ -2,14 +2,31 @@
 package emhcasa
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math"
+	"math/big"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/iseeberg79/emh-casa-go/obis"
+	"golang.org/x/net/http/httpproxy"
 )
 
 // Client is a CASA 1.1 smart meter gateway client.
@@ -17,10 +34,23 @@ import (
 type Client struct {
 	httpClient    *http.Client
 	hostTransport *hostHeaderTransport
-	uri           string
-	meterID       string
+	transport     *http.Transport
+	tlsConfig     *tls.Config
+	rediscover    func() (string, error) // nil unless auto re-discovery is enabled
+
+	discovery sync.Mutex // serializes meter ID discovery
+
+	mu      sync.Mutex // guards uri, which changes on re-discovery, and meterID
+	uri     string
+	meterID string
 }
 
+var _ Gateway = (*Client)(nil)
+
+// ErrNoValues is returned if the gateway response contains no value that
+// could be converted to a Reading.
+var ErrNoValues = errors.New("no valid meter values found")
+
 // NewClientDiscover creates a new CASA client with full auto-discovery.
 // Discovers the gateway via mDNS and the meter ID from available contracts.
 func NewClientDiscover(user, password string) (*Client, error) {
@@ -38,6 +68,58 @@ func NewClientDiscover(user, password string) (*Client, error) {
 // For SSH tunnels, use SetHostHeader("smgw.local") after creating the client.
 // Returns an error if credentials are missing or discovery/connection fails.
 func NewClient(uri, user, password, meterID string) (*Client, error) {
+	c, err := newClient(uri, meterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == "" || password == "" {
+		return nil, fmt.Errorf("credentials are required")
+	}
+
+	// Add digest authentication
+	c.httpClient.Transport = NewDigestTransport(user, password, c.hostTransport)
+
+	return c, nil
+}
+
+// NewClientCredentials creates a new CASA client with HTTP digest authentication
+// using credentials from the given provider instead of fixed strings.
+// Credentials are requested for every request, so rotated passwords are picked up
+// without re-creating the client. uri and meterID behave as for NewClient.
+func NewClientCredentials(uri string, provider CredentialsProvider, meterID string) (*Client, error) {
+	c, err := newClient(uri, meterID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.httpClient.Transport = &credentialsTransport{
+		provider: provider,
+		base:     c.hostTransport,
+	}
+
+	return c, nil
+}
+
+// NewClientCertificate creates a new CASA client authenticating with a TLS client
+// certificate instead of HTTP digest authentication, for HAN interfaces configured
+// for certificate-based access (TR-03109).
+//
+// uri and meterID behave as for NewClient. Use tls.LoadX509KeyPair to load
+// the certificate and key from PEM files.
+func NewClientCertificate(uri string, cert tls.Certificate, meterID string) (*Client, error) {
+	c, err := newClient(uri, meterID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.tlsConfig.Certificates = []tls.Certificate{cert}
+
+	return c, nil
+}
+
+// newClient creates an unauthenticated client, discovering the gateway if uri is empty
+func newClient(uri, meterID string) (*Client, error) {
 	// Auto-discover gateway if URI is empty
 	if uri == "" {
 		discoveredURI, err := DiscoverGatewayURI()
@@ -47,14 +129,11 @@ func NewClient(uri, user, password, meterID string) (*Client, error) {
 		uri = discoveredURI
 	}
 
-	if user == "" || password == "" {
-		return nil, fmt.Errorf("credentials are required")
-	}
-
 	uri = defaultScheme(uri, "https")
 
 	// Create HTTP client with custom transport for self-signed certs
 	customTransport := &http.Transport{
+		Proxy: proxyFromEnvironment(),
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true,
 		},
@@ -67,14 +146,11 @@ func NewClient(uri, user, password, meterID string) (*Client, error) {
 		host: "", // empty = use default from request
 	}
 
-	// Add digest authentication
-	httpClient := &http.Client{
-		Transport: NewDigestTransport(user, password, hostTransport),
-	}
-
 	c := &Client{
-		httpClient:    httpClient,
+		httpClient:    &http.Client{Transport: hostTransport},
 		hostTransport: hostTransport,
+		transport:     customTransport,
+		tlsConfig:     customTransport.TLSClientConfig,
 		uri:           uri,
 		meterID:       meterID,
 	}
@@ -86,23 +162,55 @@ func NewClient(uri, user, password, meterID string) (*Client, error) {
 // This is automatically called by MeterID if no meter ID is provided.
 // Returns an error if no contract with sensor domains is found.
 func (c *Client) DiscoverMeterID() error {
+	c.discovery.Lock()
+	defer c.discovery.Unlock()
+
+	return c.discoverMeterID(context.Background())
+}
+
+// meter returns the meter ID, discovering it once if none is set
+func (c *Client) meter(ctx context.Context) (string, error) {
+	c.discovery.Lock()
+	defer c.discovery.Unlock()
+
+	if id := c.currentMeterID(); id != "" {
+		return id, nil
+	}
+
+	if err := c.discoverMeterID(ctx); err != nil {
+		return "", fmt.Errorf("failed to discover meter ID: %w", err)
+	}
+
+	return c.currentMeterID(), nil
+}
+
+// currentMeterID returns the configured or discovered meter ID
+func (c *Client) currentMeterID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.meterID
+}
+
+// discoverMeterID implements DiscoverMeterID, callers hold c.discovery
+func (c *Client) discoverMeterID(ctx context.Context) error {
 	var contracts []string
-	uri := fmt.Sprintf("%s/json/metering/derived", c.uri)
 
-	if err := c.getJSON(uri, &contracts); err != nil {
+	if err := c.getJSON(ctx, "/json/metering/derived", &contracts); err != nil {
 		return fmt.Errorf("failed to get contracts: %w", err)
 	}
 
 	for _, id := range contracts {
 		var contract DerivedContract
-		uri := fmt.Sprintf("%s/json/metering/derived/%s", c.uri, id)
 
-		if err := c.getJSON(uri, &contract); err != nil {
+		if err := c.getJSON(ctx, "/json/metering/derived/"+id, &contract); err != nil {
 			continue
 		}
 
 		if len(contract.SensorDomains) > 0 {
+			c.mu.Lock()
 			c.meterID = contract.SensorDomains[0]
+			c.mu.Unlock()
 			return nil
 		}
 	}
@@ -110,77 +218,184 @@ func (c *Client) DiscoverMeterID() error {
 	return fmt.Errorf("no contract with sensor domains found")
 }
 
+// Contracts returns all metering contracts configured on the gateway, including
+// their TAF type and sensor domains (meter IDs). Use it to tell consumption and
+// feed-in meters apart or to select a meter ID explicitly.
+func (c *Client) Contracts() ([]DerivedContract, error) {
+	var ids []string
+
+	if err := c.getJSON(context.Background(), "/json/metering/derived", &ids); err != nil {
+		return nil, fmt.Errorf("failed to get contracts: %w", err)
+	}
+
+	contracts := make([]DerivedContract, 0, len(ids))
+	for _, id := range ids {
+		contract := DerivedContract{ID: id}
+
+		if err := c.getJSON(context.Background(), "/json/metering/derived/"+id, &contract); err != nil {
+			return nil, fmt.Errorf("failed to get contract %s: %w", id, err)
+		}
+
+		contracts = append(contracts, contract)
+	}
+
+	return contracts, nil
+}
+
+// TariffRegisters returns the time-of-use tariff registers reported by the
+// meter, e.g. ["1.8.1", "1.8.2", "2.8.1", "2.8.2"], in OBIS order. Tariff
+// registers are only looked up if one of the meter's contracts is a TAF-2
+// contract, otherwise TariffRegisters returns nil.
+func (c *Client) TariffRegisters() ([]string, error) {
+	meterID, err := c.meter(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	contracts, err := c.Contracts()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(contracts, func(contract DerivedContract) bool {
+		return contract.TafType == "TAF-2" && slices.Contains(contract.SensorDomains, meterID)
+	}) {
+		return nil, nil
+	}
+
+	readings, err := c.GetReadings()
+	if err != nil {
+		return nil, err
+	}
+
+	var registers []string
+	for _, r := range readings {
+		if (obis.Match("1.8.*", r.OBIS) || obis.Match("2.8.*", r.OBIS)) && !strings.HasSuffix(r.OBIS, ".0") {
+			registers = append(registers, r.OBIS)
+		}
+	}
+	slices.Sort(registers)
+
+	return slices.Compact(registers), nil
+}
+
 // GetMeterValues fetches and parses current meter readings from the gateway.
 // If no meter ID is set, it will be automatically discovered from available contracts.
 //
 // Returns a map of OBIS codes to float64 values. OBIS codes use the format C.D.E
-// where common values include:
+// where common values include (see the obis package for constants):
 //   - 16.7.0: Current power (W)
 //   - 1.8.0: Total imported energy (kWh)
 //   - 2.8.0: Total exported energy (kWh)
+//   - 1.8.1, 1.8.2, 2.8.1, 2.8.2: Tariff registers (kWh), if the meter reports them
 //   - 31.7.0, 51.7.0, 71.7.0: Phase currents (A)
 //   - 32.7.0, 52.7.0, 72.7.0: Phase voltages (V)
+//   - 9.7.0: Apparent power (VA), 13.7.0: Power factor, if the meter reports them
+//
+// Values of gas, water or heat meters bridged by the gateway are keyed in
+// A-B:C.D.E notation, e.g. "7-0:3.0.0" for gas volume (m³).
 //
 // Returns an error if meter ID discovery fails, the gateway request fails, or no valid values are found.
 func (c *Client) GetMeterValues() (map[string]float64, error) {
-	if c.meterID == "" {
-		if err := c.DiscoverMeterID(); err != nil {
-			return nil, fmt.Errorf("failed to discover meter ID: %w", err)
-		}
+	readings, err := c.GetReadings()
+	if err != nil {
+		return nil, err
+	}
+
+	return Values(readings), nil
+}
+
+// GetReadings fetches current meter readings like GetMeterValues, but keeps the
+// value and scaler exactly as reported by the gateway next to the converted value,
+// e.g. for consumers that must reproduce billing-relevant values (Eichrecht).
+func (c *Client) GetReadings() ([]Reading, error) {
+	return c.GetReadingsContext(context.Background())
+}
+
+// GetReadingsContext is like GetReadings, aborting the requests when ctx is done.
+func (c *Client) GetReadingsContext(ctx context.Context) ([]Reading, error) {
+	meterID, err := c.meter(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	var reading MeterReading
-	uri := fmt.Sprintf("%s/json/metering/origin/%s/extended", c.uri, c.meterID)
+	path := fmt.Sprintf("/json/metering/origin/%s/extended", meterID)
 
-	if err := c.getJSON(uri, &reading); err != nil {
+	if err := c.getJSON(ctx, path, &reading); err != nil {
 		return nil, fmt.Errorf("failed to get meter values: %w", err)
 	}
 
-	values := make(map[string]float64)
+	var readings []Reading
+	received := time.Now()
 
 	for _, item := range reading.Values {
-		obis, err := convertToOBIS(item.LogicalName)
-		if err != nil {
-			continue
-		}
-
-		raw, err := strconv.ParseFloat(item.Value, 64)
-		if err != nil {
-			continue
+		if r, ok := newReading(item); ok {
+			r.ReceivedAt, r.Timestamp = received, received
+			if t, err := time.Parse(time.RFC3339, item.CaptureTime); err == nil {
+				r.Timestamp = t
+			}
+			readings = append(readings, r)
 		}
+	}
 
-		val := raw * math.Pow(10, float64(item.Scaler))
-
-		switch item.Unit {
-		case 27: // W (Watt)
-			values[obis] = val
-		case 30: // Wh (Watthour) → kWh
-			values[obis] = val / 1000
-		case 33: // A (Ampere)
-			values[obis] = val
-		case 35: // V (Volt)
-			values[obis] = val
-		case 44: // Hz (Hertz)
-			values[obis] = val
-		}
+	if len(readings) == 0 {
+		return nil, ErrNoValues
 	}
 
-	if len(values) == 0 {
-		return nil, fmt.Errorf("no valid meter values found")
+	return readings, nil
+}
+
+// Subscribe polls meter values at the given interval and delivers them on the
+// returned channel until ctx is cancelled, then closes it. It is intended for
+// gateways configured for high-frequency readings (TAF-14) with intervals of 1-2 seconds.
+//
+// Polls never overlap and reuse the client's connection and digest session.
+// If the consumer falls behind, stale updates are dropped in favor of the newest one.
+func (c *Client) Subscribe(ctx context.Context, interval time.Duration) (<-chan MeterUpdate, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid subscribe interval: %v", interval)
 	}
 
-	return values, nil
+	updates := make(chan MeterUpdate, 1)
+
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			readings, err := c.GetReadingsContext(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+
+			update := MeterUpdate{Time: time.Now(), Err: err}
+			if err == nil {
+				update.Values = Values(readings)
+			}
+
+			// drop a stale update the consumer hasn't picked up yet
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- update
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates, nil
 }
 
 // MeterID returns the configured meter ID or discovers automatically.
 func (c *Client) MeterID() (string, error) {
-	// Discover meter ID if not provided
-	if c.meterID == "" {
-		if err := c.DiscoverMeterID(); err != nil {
-			return "", fmt.Errorf("failed to discover meter ID: %w", err)
-		}
-	}
-	return c.meterID, nil
+	return c.meter(context.Background())
 }
 
 // SetHostHeader overrides the Host header for all requests.
@@ -189,16 +404,174 @@ func (c *Client) SetHostHeader(host string) {
 	c.hostTransport.host = host
 }
 
-// getJSON makes a JSON API call and unmarshals the response
-func (c *Client) getJSON(uri string, result interface{}) error {
-	resp, err := c.httpClient.Get(uri)
+// SetProxy routes all gateway requests through the given proxy, overriding the
+// proxy environment variables. Supported schemes are http, https and socks5,
+// e.g. "socks5://localhost:1080" for an SSH dynamic forward. A nil URL disables proxying.
+func (c *Client) SetProxy(proxyURL *url.URL) {
+	c.transport.Proxy = nil
+	if proxyURL != nil {
+		c.transport.Proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// SetInsecureSkipVerify controls whether the gateway certificate is verified.
+// Verification is skipped by default because CASA gateways use self-signed certificates.
+func (c *Client) SetInsecureSkipVerify(skip bool) {
+	c.tlsConfig.InsecureSkipVerify = skip
+}
+
+// SetRootCAs enables certificate verification against the given pool,
+// e.g. containing the gateway's exported HAN certificate.
+func (c *Client) SetRootCAs(pool *x509.CertPool) {
+	c.tlsConfig.RootCAs = pool
+	c.tlsConfig.InsecureSkipVerify = false
+}
+
+// SetServerName overrides the host name used to verify the gateway certificate,
+// for gateways reached by IP address whose certificate is issued for a name.
+func (c *Client) SetServerName(name string) {
+	c.tlsConfig.ServerName = name
+}
+
+// SetTLSVersion restricts the TLS protocol versions used to connect to the gateway,
+// e.g. tls.VersionTLS10 for older firmwares. Zero values keep Go's defaults.
+func (c *Client) SetTLSVersion(minVersion, maxVersion uint16) {
+	c.tlsConfig.MinVersion = minVersion
+	c.tlsConfig.MaxVersion = maxVersion
+}
+
+// SetCipherSuites sets the TLS 1.0-1.2 cipher suites offered to the gateway.
+// Legacy suites from tls.InsecureCipherSuites must be listed explicitly.
+// TLS 1.3 cipher suites are not configurable.
+func (c *Client) SetCipherSuites(suites []uint16) {
+	c.tlsConfig.CipherSuites = suites
+}
+
+// EnableLegacyTLS allows TLS 1.0/1.1 and all cipher suites known to Go, including
+// insecure ones, for old gateway firmwares that can't negotiate modern parameters.
+// This weakens transport security and logs a warning; prefer SetTLSVersion and
+// SetCipherSuites with the minimal set required by the gateway.
+func (c *Client) EnableLegacyTLS() {
+	log.Printf("emhcasa: WARNING: legacy TLS enabled for %s, connection security is reduced", c.gatewayURI())
+
+	var suites []uint16
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites = append(suites, s.ID)
+	}
+
+	c.tlsConfig.MinVersion = tls.VersionTLS10
+	c.tlsConfig.CipherSuites = suites
+}
+
+// SetCertificateFingerprint pins the gateway certificate by its SHA-256 fingerprint
+// (hex encoded, colons optional). Connections to a gateway presenting a different
+// certificate fail. Use CertificateFingerprint to obtain the current fingerprint.
+func (c *Client) SetCertificateFingerprint(fingerprint string) error {
+	want, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("invalid certificate fingerprint: %s", fingerprint)
+	}
+
+	c.tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) > 0 {
+			if sum := sha256.Sum256(rawCerts[0]); bytes.Equal(sum[:], want) {
+				return nil
+			}
+		}
+		return fmt.Errorf("gateway certificate does not match pinned fingerprint")
+	}
+
+	return nil
+}
+
+// CertificateFingerprint connects to the gateway and returns the SHA-256 fingerprint
+// of the certificate it presents, formatted as colon-separated hex (e.g. "AB:CD:...").
+// The connection uses the client's proxy, Host header and TLS settings, but the
+// certificate is not verified; compare the result out-of-band before pinning it
+// with SetCertificateFingerprint (trust on first use).
+func (c *Client) CertificateFingerprint() (string, error) {
+	transport := c.transport.Clone()
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.TLSClientConfig.VerifyPeerCertificate = nil
+	transport.DisableKeepAlives = true
+
+	req, err := http.NewRequest(http.MethodGet, c.gatewayURI()+"/", nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid gateway URI: %w", err)
+	}
+
+	resp, err := (&hostHeaderTransport{base: transport, host: c.hostTransport.host}).RoundTrip(req)
+	if err != nil {
+		return "", fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return "", fmt.Errorf("gateway presented no certificate")
+	}
+
+	sum := sha256.Sum256(resp.TLS.PeerCertificates[0].Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":"), nil
+}
+
+// SetAutoRediscover enables or disables automatic gateway re-discovery.
+// When enabled, a request failing with a network error triggers mDNS discovery;
+// if the gateway is found at a new address, the client switches to it and
+// retries the request once. Useful for gateways that change their link-local
+// IPv6 address after firmware updates.
+func (c *Client) SetAutoRediscover(enabled bool) {
+	c.rediscover = nil
+	if enabled {
+		c.rediscover = DiscoverGatewayURI
+	}
+}
+
+// gatewayURI returns the current gateway URI
+func (c *Client) gatewayURI() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.uri
+}
+
+// reconnect switches to uri, dropping open connections and the digest session
+func (c *Client) reconnect(uri string) {
+	c.mu.Lock()
+	c.uri = uri
+	c.mu.Unlock()
+
+	c.transport.CloseIdleConnections()
+
+	if t, ok := c.httpClient.Transport.(interface{ reset() }); ok {
+		t.reset()
+	}
+}
+
+// getJSON makes a JSON API call relative to the gateway URI and unmarshals the response
+func (c *Client) getJSON(ctx context.Context, path string, result interface{}) error {
+	uri := c.gatewayURI()
+	resp, err := c.get(ctx, uri+path)
+	if err != nil && ctx.Err() == nil && c.rediscover != nil {
+		// another request may have switched to the new address already
+		if found, derr := c.rediscover(); derr == nil && found != uri {
+			if found != c.gatewayURI() {
+				c.reconnect(found)
+			}
+			resp, err = c.get(ctx, found+path)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &StatusError{Code: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -213,28 +586,111 @@ func (c *Client) getJSON(uri string, result interface{}) error {
 	return nil
 }
 
-// convertToOBIS converts CASA logical name to OBIS C.D.E format
-func convertToOBIS(logicalName string) (string, error) {
-	hex := strings.SplitN(logicalName, ".", 2)[0]
+// newReading converts a gateway value, reporting false for unparseable values and unsupported units
+func newReading(item MeterValue) (Reading, bool) {
+	code, err := convertToOBIS(item.LogicalName)
+	if err != nil {
+		return Reading{}, false
+	}
+
+	raw, err := strconv.ParseFloat(item.Value, 64)
+	if err != nil {
+		return Reading{}, false
+	}
+
+	r := Reading{
+		OBIS:        code,
+		LogicalName: item.LogicalName,
+		Value:       raw * math.Pow(10, float64(item.Scaler)),
+		ValueMilli:  milli(item.Value, item.Scaler+3),
+		Unit:        item.Unit,
+		RawValue:    item.Value,
+		Scaler:      item.Scaler,
+	}
+
+	if len(item.Status) > 0 && string(item.Status) != "null" {
+		r.StatusRaw = strings.Trim(string(item.Status), `"`)
+	}
+
+	// status semantics are vendor-specific, treat any set flag as invalid
+	if status, err := strconv.ParseUint(r.StatusRaw, 0, 64); r.StatusRaw != "" && (err != nil || status != 0) {
+		r.Quality = QualityInvalid
+	}
+
+	switch item.Unit {
+	case UnitWatt, UnitVoltAmpere, UnitVar, UnitAmpere, UnitVolt, UnitHertz,
+		UnitCubicMetre, UnitCelsius, UnitPercent:
+		return r, true
+	case UnitWattHour, UnitVoltAmpereHour, UnitVarHour: // → kWh, kVAh, kvarh
+		r.Value /= 1000
+		r.ValueMilli = milli(item.Value, item.Scaler)
+		return r, true
+	case UnitDimensionless:
+		// only power factors, other unitless registers are status words
+		if e, ok := obis.Lookup(code); ok && e.Quantity == obis.QuantityPowerFactor {
+			return r, true
+		}
+	}
 
-	if len(hex) != 12 {
-		return "", fmt.Errorf("unexpected logical name: %s", logicalName)
+	return Reading{}, false
+}
+
+// milli returns raw * 10^exp rounded to an integer without float rounding errors,
+// or 0 if raw is not a number or the result exceeds int64
+func milli(raw string, exp int) int64 {
+	v, ok := new(big.Rat).SetString(raw)
+	if !ok {
+		return 0
+	}
+
+	pow := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(exp, -exp))), nil))
+	if exp < 0 {
+		pow.Inv(pow)
 	}
 
-	c, err := strconv.ParseInt(hex[4:6], 16, 64)
+	res, err := strconv.ParseInt(v.Mul(v, pow).FloatString(0), 10, 64)
 	if err != nil {
-		return "", err
+		return 0
 	}
-	d, err := strconv.ParseInt(hex[6:8], 16, 64)
+
+	return res
+}
+
+// get sends a GET request
+func (c *Client) get(ctx context.Context, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	e, err := strconv.ParseInt(hex[8:10], 16, 64)
+	return c.httpClient.Do(req)
+}
+
+// convertToOBIS converts CASA logical name to OBIS C.D.E format, or A-B:C.D.E
+// for non-electricity media
+func convertToOBIS(logicalName string) (string, error) {
+	code, err := obis.ParseHex(strings.SplitN(logicalName, ".", 2)[0])
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("%d.%d.%d", c, d, e), nil
+	return code.Key(), nil
+}
+
+// proxyFromEnvironment returns a proxy function honoring HTTPS_PROXY, HTTP_PROXY
+// and NO_PROXY like http.ProxyFromEnvironment. ALL_PROXY is used for all requests
+// without a scheme-specific proxy. Unlike http.ProxyFromEnvironment, the
+// environment is read on every call, i.e. per client.
+func proxyFromEnvironment() func(*http.Request) (*url.URL, error) {
+	all := cmp.Or(os.Getenv("ALL_PROXY"), os.Getenv("all_proxy"))
+
+	cfg := httpproxy.FromEnvironment()
+	cfg.HTTPProxy = cmp.Or(cfg.HTTPProxy, all)
+	cfg.HTTPSProxy = cmp.Or(cfg.HTTPSProxy, all)
+
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
 }
 
 // defaultScheme adds a default scheme if missing
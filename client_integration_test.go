@@ -2,6 +2,10 @@ package emhcasa
 
 import (
 	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -262,3 +266,57 @@ func isValidOBIS(obis string) bool {
 
 	return dotCount == 2 // Must have exactly 2 dots
 }
+
+// TestLiveGateway runs against a real gateway and logs a capability report.
+// It is skipped unless CASA_URI, CASA_USER and CASA_PASS are set. Run with:
+//
+//	CASA_URI=https://192.168.33.2 CASA_USER=admin CASA_PASS=secret go test -v -run TestLiveGateway
+//
+// CASA_METER optionally selects the meter ID, CASA_HOST sets a custom Host header.
+func TestLiveGateway(t *testing.T) {
+	uri, user, pass := os.Getenv("CASA_URI"), os.Getenv("CASA_USER"), os.Getenv("CASA_PASS")
+	if testing.Short() || uri == "" || user == "" || pass == "" {
+		t.Skip("set CASA_URI, CASA_USER and CASA_PASS to run against a live gateway")
+	}
+
+	c, err := NewClient(uri, user, pass, os.Getenv("CASA_METER"))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if host := os.Getenv("CASA_HOST"); host != "" {
+		c.SetHostHeader(host)
+	}
+
+	if fp, err := c.CertificateFingerprint(); err != nil {
+		t.Errorf("CertificateFingerprint() error = %v", err)
+	} else {
+		t.Logf("certificate: %s", fp)
+	}
+
+	meterID, err := c.MeterID()
+	if err != nil {
+		t.Fatalf("MeterID() error = %v", err)
+	}
+	t.Logf("meter ID: %s", meterID)
+
+	values, err := c.GetMeterValues()
+	if err != nil {
+		t.Fatalf("GetMeterValues() error = %v", err)
+	}
+
+	codes := make([]string, 0, len(values))
+	for obis := range values {
+		codes = append(codes, obis)
+	}
+	sort.Strings(codes)
+
+	var report strings.Builder
+	for _, obis := range codes {
+		report.WriteString("\n  " + obis + " = " + strconv.FormatFloat(values[obis], 'f', -1, 64))
+	}
+	t.Logf("%d values:%s", len(values), report.String())
+
+	grid := NewGridSnapshot(values)
+	t.Logf("grid: import %.0f W, export %.0f W, imported %.3f kWh, exported %.3f kWh, phases %v W",
+		grid.ImportW, grid.ExportW, grid.ImportkWh, grid.ExportkWh, grid.PerPhase)
+}
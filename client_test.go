@@ -1,8 +1,28 @@
 package emhcasa
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // TestDefaultScheme tests scheme addition
@@ -174,3 +194,842 @@ func TestNewClient(t *testing.T) {
 		})
 	}
 }
+
+// TestAutoRediscover tests switching to a re-discovered gateway address after a network error
+func TestAutoRediscover(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient("https://127.0.0.1:1", "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if _, err := c.GetMeterValues(); err == nil {
+		t.Fatal("GetMeterValues() expected error without re-discovery")
+	}
+
+	c.SetAutoRediscover(true)
+	c.rediscover = func() (string, error) { return srv.URL, nil }
+
+	// concurrent callers, e.g. a poller and the REST server, share the client
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			values, err := c.GetMeterValues()
+			if err != nil {
+				t.Errorf("GetMeterValues() error = %v", err)
+				return
+			}
+			if values["16.7.0"] != 2500 {
+				t.Errorf("GetMeterValues()[16.7.0] = %v, want 2500", values["16.7.0"])
+			}
+		}()
+	}
+	wg.Wait()
+
+	if uri := c.gatewayURI(); uri != srv.URL {
+		t.Errorf("client uri = %v, want %v", uri, srv.URL)
+	}
+}
+
+// TestCertificateFingerprint tests fetching and pinning the gateway certificate
+func TestCertificateFingerprint(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	want := hex.EncodeToString(sum[:])
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	got, err := c.CertificateFingerprint()
+	if err != nil {
+		t.Fatalf("CertificateFingerprint() error = %v", err)
+	}
+	if !strings.EqualFold(strings.ReplaceAll(got, ":", ""), want) {
+		t.Errorf("CertificateFingerprint() = %v, want %v", got, want)
+	}
+
+	// gateways only reachable through a proxy, e.g. an SSH jump host
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+
+		upstream, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}))
+	defer proxy.Close()
+
+	proxied, err := NewClient("https://smgw.invalid", "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxied.SetProxy(proxyURL)
+
+	if fp, err := proxied.CertificateFingerprint(); err != nil || fp != got {
+		t.Errorf("CertificateFingerprint() through proxy = %v, %v, want %v", fp, err, got)
+	}
+
+	if err := c.SetCertificateFingerprint("invalid"); err == nil {
+		t.Error("SetCertificateFingerprint() expected error for invalid fingerprint")
+	}
+
+	if err := c.SetCertificateFingerprint(got); err != nil {
+		t.Fatalf("SetCertificateFingerprint() error = %v", err)
+	}
+	if _, err := c.GetMeterValues(); err != nil {
+		t.Errorf("GetMeterValues() with matching pin error = %v", err)
+	}
+
+	// a fresh client avoids reusing the already verified connection
+	c, _ = NewClient(srv.URL, "admin", "pass", "123")
+	if err := c.SetCertificateFingerprint(strings.Repeat("00", sha256.Size)); err != nil {
+		t.Fatalf("SetCertificateFingerprint() error = %v", err)
+	}
+	if _, err := c.GetMeterValues(); err == nil {
+		t.Error("GetMeterValues() with mismatching pin expected error")
+	}
+}
+
+// TestCertificateVerification tests verifying the gateway certificate against custom roots
+func TestCertificateVerification(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	tests := []struct {
+		name      string
+		configure func(c *Client)
+		wantErr   bool
+	}{
+		{
+			name:      "verification against system roots fails",
+			configure: func(c *Client) { c.SetInsecureSkipVerify(false) },
+			wantErr:   true,
+		},
+		{
+			name:      "custom root CA",
+			configure: func(c *Client) { c.SetRootCAs(pool) },
+			wantErr:   false,
+		},
+		{
+			name: "custom root CA with matching server name",
+			configure: func(c *Client) {
+				c.SetRootCAs(pool)
+				c.SetServerName("example.com")
+			},
+			wantErr: false,
+		},
+		{
+			name: "custom root CA with wrong server name",
+			configure: func(c *Client) {
+				c.SetRootCAs(pool)
+				c.SetServerName("smgw.local")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(srv.URL, "admin", "pass", "123")
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			tt.configure(c)
+
+			if _, err := c.GetMeterValues(); (err != nil) != tt.wantErr {
+				t.Errorf("GetMeterValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestNewClientCertificate tests authentication with a TLS client certificate
+func TestNewClientCertificate(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	c, err := NewClientCertificate(srv.URL, cert, "123")
+	if err != nil {
+		t.Fatalf("NewClientCertificate() error = %v", err)
+	}
+
+	values, err := c.GetMeterValues()
+	if err != nil {
+		t.Fatalf("GetMeterValues() error = %v", err)
+	}
+	if values["16.7.0"] != 2500 {
+		t.Errorf("GetMeterValues()[16.7.0] = %v, want 2500", values["16.7.0"])
+	}
+}
+
+// TestNewClientCredentials tests digest authentication with rotating credentials
+func TestNewClientCredentials(t *testing.T) {
+	var gotUser string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if auth == "" {
+			w.Header().Set("WWW-Authenticate", `Digest realm="smgw", nonce="abc", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		gotUser = strings.SplitN(strings.SplitN(auth, `username="`, 2)[1], `"`, 2)[0]
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer srv.Close()
+
+	user := "consumer1"
+	provider := CredentialsFunc(func() (string, string, error) {
+		return user, "secret", nil
+	})
+
+	c, err := NewClientCredentials(srv.URL, provider, "123")
+	if err != nil {
+		t.Fatalf("NewClientCredentials() error = %v", err)
+	}
+
+	for _, want := range []string{"consumer1", "consumer2"} {
+		user = want
+		if _, err := c.GetMeterValues(); err != nil {
+			t.Fatalf("GetMeterValues() error = %v", err)
+		}
+		if gotUser != want {
+			t.Errorf("authenticated as %v, want %v", gotUser, want)
+		}
+	}
+}
+
+// TestTLSVersion tests connecting to gateways limited to older TLS versions
+func TestTLSVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	srv.TLS = &tls.Config{
+		MinVersion:   tls.VersionTLS11,
+		MaxVersion:   tls.VersionTLS11,
+		CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA},
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if _, err := c.GetMeterValues(); err == nil {
+		t.Fatal("GetMeterValues() expected error with default TLS settings")
+	}
+
+	c.SetTLSVersion(tls.VersionTLS10, tls.VersionTLS12)
+	c.SetCipherSuites([]uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA})
+
+	if _, err := c.GetMeterValues(); err != nil {
+		t.Errorf("GetMeterValues() error = %v", err)
+	}
+
+	c, err = NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	c.EnableLegacyTLS()
+
+	if _, err := c.GetMeterValues(); err != nil {
+		t.Errorf("GetMeterValues() with legacy TLS error = %v", err)
+	}
+}
+
+// TestProxy tests routing requests through explicit and environment proxies
+func TestProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	want := "http://smgw.invalid/json/metering/origin/123/extended"
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		configure func(c *Client)
+		want      string
+	}{
+		{
+			name:      "explicit proxy",
+			configure: func(c *Client) { c.SetProxy(proxyURL) },
+			want:      want,
+		},
+		{
+			name:      "HTTP_PROXY",
+			env:       map[string]string{"HTTP_PROXY": proxy.URL},
+			configure: func(c *Client) {},
+			want:      want,
+		},
+		{
+			name:      "ALL_PROXY fallback",
+			env:       map[string]string{"ALL_PROXY": proxy.URL},
+			configure: func(c *Client) {},
+			want:      want,
+		},
+		{
+			name:      "NO_PROXY",
+			env:       map[string]string{"ALL_PROXY": proxy.URL, "NO_PROXY": "smgw.invalid"},
+			configure: func(c *Client) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy", "NO_PROXY", "no_proxy", "REQUEST_METHOD"} {
+				t.Setenv(env, "")
+			}
+			for env, value := range tt.env {
+				t.Setenv(env, value)
+			}
+
+			proxied = ""
+			c, err := NewClient("http://smgw.invalid", "admin", "pass", "123")
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			tt.configure(c)
+
+			if _, err := c.GetMeterValues(); (err != nil) != (tt.want == "") {
+				t.Fatalf("GetMeterValues() error = %v", err)
+			}
+			if proxied != tt.want {
+				t.Errorf("proxied request = %q, want %q", proxied, tt.want)
+			}
+		})
+	}
+}
+
+// TestGetMeterValuesTariffRegisters tests TAF-2 tariff registers are returned next to the totals
+func TestGetMeterValuesTariffRegisters(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[
+			{"value":"3000000","unit":30,"scaler":-1,"logical_name":"0100010800FF.255"},
+			{"value":"2000000","unit":30,"scaler":-1,"logical_name":"0100010801FF.255"},
+			{"value":"1000000","unit":30,"scaler":-1,"logical_name":"0100010802FF.255"},
+			{"value":"50000","unit":30,"scaler":-1,"logical_name":"0100020801FF.255"},
+			{"value":"0","unit":30,"scaler":-1,"logical_name":"0100020802FF.255"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	values, err := c.GetMeterValues()
+	if err != nil {
+		t.Fatalf("GetMeterValues() error = %v", err)
+	}
+
+	want := map[string]float64{"1.8.0": 300, "1.8.1": 200, "1.8.2": 100, "2.8.1": 5, "2.8.2": 0}
+	for obis, v := range want {
+		got, ok := values[obis]
+		if !ok {
+			t.Errorf("GetMeterValues() missing %s", obis)
+			continue
+		}
+		if math.Abs(got-v) > 0.00001 {
+			t.Errorf("GetMeterValues()[%s] = %v, want %v", obis, got, v)
+		}
+	}
+}
+
+// TestTariffRegisters tests discovery of the tariff registers of TAF-2 contracts
+func TestTariffRegisters(t *testing.T) {
+	tests := []struct {
+		name string
+		taf  string
+		want []string
+	}{
+		{name: "TAF-2", taf: "TAF-2", want: []string{"1.8.1", "1.8.2", "2.8.1"}},
+		{name: "TAF-1", taf: "TAF-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/json/metering/derived":
+					fmt.Fprint(w, `["c1"]`)
+				case "/json/metering/derived/c1":
+					fmt.Fprintf(w, `{"taf_type":%q,"sensor_domains":["123"]}`, tt.taf)
+				case "/json/metering/origin/123/extended":
+					fmt.Fprint(w, `{"values":[
+						{"value":"50000","unit":30,"scaler":-1,"logical_name":"0100020801FF.255"},
+						{"value":"3000000","unit":30,"scaler":-1,"logical_name":"0100010800FF.255"},
+						{"value":"1000000","unit":30,"scaler":-1,"logical_name":"0100010802FF.255"},
+						{"value":"2000000","unit":30,"scaler":-1,"logical_name":"0100010801FF.255"},
+						{"value":"500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}
+					]}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			c, err := NewClient(srv.URL, "admin", "pass", "123")
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			got, err := c.TariffRegisters()
+			if err != nil {
+				t.Fatalf("TariffRegisters() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("TariffRegisters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGetReadings tests that raw gateway values are kept next to converted values
+func TestGetReadings(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[
+			{"value":"123456789012","unit":30,"scaler":-1,"logical_name":"0100010800FF.255"},
+			{"value":"invalid","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	readings, err := c.GetReadings()
+	if err != nil {
+		t.Fatalf("GetReadings() error = %v", err)
+	}
+
+	want := Reading{OBIS: "1.8.0", LogicalName: "0100010800FF.255", Value: 12345678.9012, ValueMilli: 12345678901, Unit: UnitWattHour, RawValue: "123456789012", Scaler: -1}
+	if len(readings) != 1 {
+		t.Fatalf("GetReadings() = %+v, want [%+v]", readings, want)
+	}
+	if got := readings[0]; got.OBIS != want.OBIS || got.LogicalName != want.LogicalName || got.ValueMilli != want.ValueMilli || got.Unit != want.Unit || got.RawValue != want.RawValue ||
+		got.Scaler != want.Scaler || math.Abs(got.Value-want.Value) > 0.00001 {
+		t.Errorf("GetReadings() = %+v, want %+v", got, want)
+	}
+}
+
+// TestGetReadingsStatus tests mapping of gateway status flags to quality
+func TestGetReadingsStatus(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[
+			{"value":"1","unit":27,"scaler":0,"logical_name":"0100010700FF.255"},
+			{"value":"2","unit":27,"scaler":0,"logical_name":"0100020700FF.255","status":0},
+			{"value":"3","unit":27,"scaler":0,"logical_name":"0100100700FF.255","status":null},
+			{"value":"4","unit":30,"scaler":0,"logical_name":"0100010800FF.255","status":"0x08"},
+			{"value":"5","unit":30,"scaler":0,"logical_name":"0100020800FF.255","status":4}
+		]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	readings, err := c.GetReadings()
+	if err != nil {
+		t.Fatalf("GetReadings() error = %v", err)
+	}
+
+	want := map[string]struct {
+		status  string
+		quality Quality
+	}{
+		"1.7.0":  {"", QualityGood},
+		"2.7.0":  {"0", QualityGood},
+		"16.7.0": {"", QualityGood},
+		"1.8.0":  {"0x08", QualityInvalid},
+		"2.8.0":  {"4", QualityInvalid},
+	}
+	if len(readings) != len(want) {
+		t.Fatalf("GetReadings() returned %d readings, want %d", len(readings), len(want))
+	}
+	for _, r := range readings {
+		if w := want[r.OBIS]; r.StatusRaw != w.status || r.Quality != w.quality {
+			t.Errorf("%s: status = %q, quality = %v, want %q, %v", r.OBIS, r.StatusRaw, r.Quality, w.status, w.quality)
+		}
+	}
+}
+
+// TestGetReadingsCaptureTime tests that gateway capture times are kept apart from retrieval time
+func TestGetReadingsCaptureTime(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[
+			{"value":"1","unit":30,"scaler":0,"logical_name":"0100010800FF.255","capture_time":"2026-03-01T12:00:00Z"},
+			{"value":"2","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	before := time.Now()
+	readings, err := c.GetReadings()
+	if err != nil {
+		t.Fatalf("GetReadings() error = %v", err)
+	}
+
+	if len(readings) != 2 {
+		t.Fatalf("GetReadings() returned %d readings, want 2", len(readings))
+	}
+	for _, r := range readings {
+		if r.ReceivedAt.Before(before) {
+			t.Errorf("%s: ReceivedAt = %v, want after %v", r.OBIS, r.ReceivedAt, before)
+		}
+	}
+	if want := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC); !readings[0].Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want capture time %v", readings[0].Timestamp, want)
+	}
+	if !readings[1].Timestamp.Equal(readings[1].ReceivedAt) {
+		t.Errorf("Timestamp = %v, want ReceivedAt %v", readings[1].Timestamp, readings[1].ReceivedAt)
+	}
+}
+
+// TestMilli tests exact fixed-point conversion of raw gateway values
+func TestMilli(t *testing.T) {
+	tests := []struct {
+		raw  string
+		exp  int
+		want int64
+	}{
+		{"123456789012345", -1, 12345678901235},
+		{"2500", 3, 2500000},
+		{"-1200", 2, -120000},
+		{"15", -1, 2},
+		{"invalid", 0, 0},
+		{"99999999999999999999", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := milli(tt.raw, tt.exp); got != tt.want {
+				t.Errorf("milli(%s, %d) = %d, want %d", tt.raw, tt.exp, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGetMeterValuesApparentPower tests apparent power and power factor registers
+func TestGetMeterValuesApparentPower(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[
+			{"value":"23000","unit":28,"scaler":-1,"logical_name":"0100090700FF.255"},
+			{"value":"800","unit":28,"scaler":0,"logical_name":"01001D0700FF.255"},
+			{"value":"950","unit":255,"scaler":-3,"logical_name":"01000D0700FF.255"},
+			{"value":"4","unit":255,"scaler":0,"logical_name":"0100600505FF.255"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	values, err := c.GetMeterValues()
+	if err != nil {
+		t.Fatalf("GetMeterValues() error = %v", err)
+	}
+
+	want := map[string]float64{"9.7.0": 2300, "29.7.0": 800, "13.7.0": 0.95}
+	if len(values) != len(want) {
+		t.Errorf("GetMeterValues() = %v, want %v", values, want)
+	}
+	for obis, v := range want {
+		if got := values[obis]; math.Abs(got-v) > 0.00001 {
+			t.Errorf("GetMeterValues()[%s] = %v, want %v", obis, got, v)
+		}
+	}
+}
+
+// TestGetMeterValuesExtendedUnits tests reactive, temperature and percent values
+func TestGetMeterValuesExtendedUnits(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[
+			{"value":"-1200","unit":29,"scaler":-1,"logical_name":"0100030700FF.255"},
+			{"value":"250000","unit":32,"scaler":0,"logical_name":"0100030800FF.255"},
+			{"value":"215","unit":9,"scaler":-1,"logical_name":"0000600900FF.255"},
+			{"value":"7","unit":56,"scaler":0,"logical_name":"0000600901FF.255"},
+			{"value":"1","unit":7,"scaler":0,"logical_name":"0000600902FF.255"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	values, err := c.GetMeterValues()
+	if err != nil {
+		t.Fatalf("GetMeterValues() error = %v", err)
+	}
+
+	want := map[string]float64{"3.7.0": -120, "3.8.0": 250, "0-0:96.9.0": 21.5, "0-0:96.9.1": 7}
+	if len(values) != len(want) {
+		t.Errorf("GetMeterValues() = %v, want %v", values, want)
+	}
+	for obis, v := range want {
+		if got := values[obis]; math.Abs(got-v) > 0.00001 {
+			t.Errorf("GetMeterValues()[%s] = %v, want %v", obis, got, v)
+		}
+	}
+}
+
+// TestGetMeterValuesGasMeter tests that non-electricity registers keep their medium
+func TestGetMeterValuesGasMeter(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[
+			{"value":"1234567","unit":13,"scaler":-3,"logical_name":"0700030000FF.255"},
+			{"value":"5000","unit":30,"scaler":0,"logical_name":"0600010000FF.255"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	values, err := c.GetMeterValues()
+	if err != nil {
+		t.Fatalf("GetMeterValues() error = %v", err)
+	}
+
+	want := map[string]float64{"7-0:3.0.0": 1234.567, "6-0:1.0.0": 5}
+	if len(values) != len(want) {
+		t.Errorf("GetMeterValues() = %v, want %v", values, want)
+	}
+	for obis, v := range want {
+		if got := values[obis]; math.Abs(got-v) > 0.00001 {
+			t.Errorf("GetMeterValues()[%s] = %v, want %v", obis, got, v)
+		}
+	}
+}
+
+// TestSubscribe tests periodic delivery of meter values until cancellation
+func TestSubscribe(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if _, err := c.Subscribe(context.Background(), 0); err == nil {
+		t.Error("Subscribe() expected error for zero interval")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := c.Subscribe(ctx, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		u := <-updates
+		if u.Err != nil {
+			t.Fatalf("update %d error = %v", i, u.Err)
+		}
+		if u.Values["16.7.0"] != 2500 {
+			t.Errorf("update %d 16.7.0 = %v, want 2500", i, u.Values["16.7.0"])
+		}
+	}
+
+	cancel()
+	for range updates {
+	}
+}
+
+// TestSubscribeCancel tests that cancelling aborts a request in flight
+func TestSubscribeCancel(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := c.Subscribe(ctx, time.Second)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case u, ok := <-updates:
+		if ok {
+			t.Errorf("update after cancel = %+v, want closed channel", u)
+		}
+	case <-time.After(time.Second):
+		t.Error("Subscribe() still running 1s after cancel")
+	}
+}
+
+// TestContracts tests retrieval of contract metadata
+func TestContracts(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json/metering/derived":
+			fmt.Fprint(w, `["c1","c2"]`)
+		case "/json/metering/derived/c1":
+			fmt.Fprint(w, `{"taf_type":"TAF-1","state":"active","sensor_domains":["1EMH0012345678"]}`)
+		case "/json/metering/derived/c2":
+			fmt.Fprint(w, `{"taf_type":"TAF-2","sensor_domains":["1EMH0012345678"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	contracts, err := c.Contracts()
+	if err != nil {
+		t.Fatalf("Contracts() error = %v", err)
+	}
+
+	if len(contracts) != 2 {
+		t.Fatalf("Contracts() returned %d contracts, want 2", len(contracts))
+	}
+	for i, want := range []struct{ id, taf, state string }{{"c1", "TAF-1", "active"}, {"c2", "TAF-2", ""}} {
+		if contracts[i].ID != want.id || contracts[i].TafType != want.taf || contracts[i].State != want.state {
+			t.Errorf("contract %d = %s/%s/%s, want %s/%s/%s", i, contracts[i].ID, contracts[i].TafType, contracts[i].State, want.id, want.taf, want.state)
+		}
+	}
+}
+
+// TestMeterIDDiscovery tests that concurrent callers discover the meter ID once
+func TestMeterIDDiscovery(t *testing.T) {
+	var discoveries atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json/metering/derived":
+			discoveries.Add(1)
+			fmt.Fprint(w, `["c1"]`)
+		case "/json/metering/derived/c1":
+			fmt.Fprint(w, `{"taf_type":"TAF-1","sensor_domains":["1EMH0012345678"]}`)
+		case "/json/metering/origin/1EMH0012345678/extended":
+			fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// e.g. a poller and exporters asking for the meter label
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if i%2 == 0 {
+				if id, err := c.MeterID(); err != nil || id != "1EMH0012345678" {
+					t.Errorf("MeterID() = %q, %v", id, err)
+				}
+			} else if _, err := c.GetReadings(); err != nil {
+				t.Errorf("GetReadings() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := discoveries.Load(); n != 1 {
+		t.Errorf("meter ID discovered %d times, want once", n)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// connOption is a gateway connection setting, configurable by flag,
+// environment variable and configuration file profile
+type connOption struct {
+	flag  string
+	env   string
+	usage string
+	field func(*gatewayConfig) *string
+}
+
+// connOptions are the connection settings in flag order
+var connOptions = []connOption{
+	{"uri", "SMGW_URI", "gateway URI, e.g. https://192.168.33.2 (default: mDNS discovery)", func(c *gatewayConfig) *string { return &c.URI }},
+	{"user", "SMGW_USER", "user for digest authentication", func(c *gatewayConfig) *string { return &c.User }},
+	{"password", "SMGW_PASSWORD", "password for digest authentication", func(c *gatewayConfig) *string { return &c.Password }},
+	{"meter", "SMGW_METER", "meter ID (default: first meter of the gateway)", func(c *gatewayConfig) *string { return &c.MeterID }},
+	{"cert", "SMGW_CERT", "PEM client certificate, instead of digest authentication", func(c *gatewayConfig) *string { return &c.CertFile }},
+	{"key", "SMGW_KEY", "PEM key of the client certificate", func(c *gatewayConfig) *string { return &c.KeyFile }},
+	{"fingerprint", "SMGW_FINGERPRINT", "SHA-256 fingerprint of the gateway certificate to pin", func(c *gatewayConfig) *string { return &c.Fingerprint }},
+	{"host", "SMGW_HOST", "Host header, e.g. smgw.local for SSH tunnels", func(c *gatewayConfig) *string { return &c.HostHeader }},
+}
+
+// connFlags are the gateway connection flags shared by the commands.
+// Flags take precedence over SMGW_* environment variables, which take
+// precedence over the gateway profile of the configuration file.
+type connFlags struct {
+	fs      *flag.FlagSet
+	flags   gatewayConfig
+	config  string
+	gateway string
+}
+
+// register adds the connection flags to fs
+func (c *connFlags) register(fs *flag.FlagSet) {
+	c.fs = fs
+
+	fs.StringVar(&c.config, "config", os.Getenv("SMGW_CONFIG"), "configuration file with gateway profiles ($SMGW_CONFIG)")
+	fs.StringVar(&c.gateway, "gateway", os.Getenv("SMGW_GATEWAY"), "gateway profile of the configuration file ($SMGW_GATEWAY)")
+
+	for _, opt := range connOptions {
+		fs.StringVar(opt.field(&c.flags), opt.flag, "", fmt.Sprintf("%s ($%s)", opt.usage, opt.env))
+	}
+}
+
+// resolve returns the connection settings after parsing the flags,
+// forwarded through the tunnel of env if any
+func (c *connFlags) resolve(env *env) (gatewayConfig, error) {
+	var gw gatewayConfig
+
+	switch {
+	case c.config != "":
+		cfg, err := loadConfig(c.config)
+		if err != nil {
+			return gw, err
+		}
+		if gw, err = cfg.profile(c.gateway); err != nil {
+			return gw, err
+		}
+
+	case c.gateway != "":
+		return gw, errors.New("gateway profiles require a configuration file (-config)")
+	}
+
+	for _, opt := range connOptions {
+		if v := os.Getenv(opt.env); v != "" {
+			*opt.field(&gw) = v
+		}
+	}
+
+	c.fs.Visit(func(f *flag.Flag) {
+		for _, opt := range connOptions {
+			if opt.flag == f.Name {
+				*opt.field(&gw) = *opt.field(&c.flags)
+			}
+		}
+	})
+
+	if env.forward != nil {
+		if err := env.forward(&gw); err != nil {
+			return gw, err
+		}
+	}
+
+	return gw, nil
+}
+
+// profile returns the named gateway, or the only one if name is empty
+func (cfg *config) profile(name string) (gatewayConfig, error) {
+	if name == "" {
+		if len(cfg.Gateways) == 1 {
+			for _, gw := range cfg.Gateways {
+				return gw, nil
+			}
+		}
+		return gatewayConfig{}, fmt.Errorf("select a gateway with -gateway: %s", strings.Join(slices.Sorted(maps.Keys(cfg.Gateways)), ", "))
+	}
+
+	gw, ok := cfg.Gateways[name]
+	if !ok {
+		return gatewayConfig{}, fmt.Errorf("unknown gateway: %s", name)
+	}
+	return gw, nil
+}
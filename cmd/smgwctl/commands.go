@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+	"github.com/iseeberg79/emh-casa-go/poll"
+)
+
+// discover prints the URI of the gateway found via mDNS
+func discover(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("discover", env.stderr)
+	timeout := fs.Duration("timeout", 2*time.Second, "mDNS query timeout")
+	hostname := fs.String("hostname", "smgw.local", "mDNS name of the gateway")
+	network := fs.String("network", "", `preferred address family, "ip4" or "ip6" (default both)`)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	uri, err := emhcasa.DiscoverGatewayURIWithConfig(emhcasa.DiscoveryConfig{
+		Hostname: *hostname,
+		Timeout:  *timeout,
+		Network:  *network,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(env.stdout, uri)
+	return nil
+}
+
+// read prints the current readings
+func read(ctx context.Context, env *env, args []string) error {
+	var conn connFlags
+	fs := newFlagSet("read", env.stderr)
+	conn.register(fs)
+	patterns := fs.String("obis", "", "comma-separated OBIS patterns to print, e.g. 1.8.*,16.7.0 (default all)")
+	quiet := fs.Bool("quiet", false, "print only the values, one per line")
+	var output format
+	registerFormat(fs, &output)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	gw, err := conn.resolve(env)
+	if err != nil {
+		return err
+	}
+
+	client, err := gw.client()
+	if err != nil {
+		return err
+	}
+
+	readings, err := client.GetReadingsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	readings = filter(readings, *patterns)
+	if len(readings) == 0 {
+		return fmt.Errorf("%w match %s", errNoReadings, *patterns)
+	}
+
+	out := newReadingsOutput(output, env.stdout, false)
+	out.quiet = *quiet
+	return out.write(time.Now(), readings)
+}
+
+// meters lists the metering contracts of the gateway
+func meters(ctx context.Context, env *env, args []string) error {
+	var conn connFlags
+	fs := newFlagSet("meters", env.stderr)
+	conn.register(fs)
+	var output format
+	registerFormat(fs, &output)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	gw, err := conn.resolve(env)
+	if err != nil {
+		return err
+	}
+
+	client, err := gw.client()
+	if err != nil {
+		return err
+	}
+
+	contracts, err := client.Contracts()
+	if err != nil {
+		return err
+	}
+
+	return writeContracts(env.stdout, output, contracts)
+}
+
+// watch polls the gateway and prints the readings until ctx is cancelled
+func watch(ctx context.Context, env *env, args []string) error {
+	var conn connFlags
+	fs := newFlagSet("watch", env.stderr)
+	conn.register(fs)
+	patterns := fs.String("obis", "", "comma-separated OBIS patterns to print (default all)")
+	interval := fs.Duration("interval", 5*time.Second, "poll interval")
+	quiet := fs.Bool("quiet", false, "print only the values, one per line")
+	var output format
+	registerFormat(fs, &output)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	gw, err := conn.resolve(env)
+	if err != nil {
+		return err
+	}
+
+	client, err := gw.client()
+	if err != nil {
+		return err
+	}
+
+	p := &poll.Poller{Gateway: client, Interval: *interval}
+	results, err := p.Stream(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := newReadingsOutput(output, env.stdout, true)
+	out.quiet = *quiet
+	for r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(env.stderr, "%s  %v\n", r.Time.Format(time.TimeOnly), r.Err)
+			continue
+		}
+
+		if err := out.write(r.Time, filter(r.Readings, *patterns)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filter returns the readings matching comma-separated OBIS patterns, all
+// if patterns is empty
+func filter(readings []emhcasa.Reading, patterns string) []emhcasa.Reading {
+	if patterns == "" {
+		return readings
+	}
+
+	f := obis.Filter(strings.Split(patterns, ","))
+
+	var res []emhcasa.Reading
+	for _, r := range readings {
+		if f.Match(r.OBIS) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// lookup prints the registry entries of an OBIS code, a CASA logical name or
+// the codes matching a search term
+func lookup(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("obis", env.stderr)
+	var output format
+	registerFormat(fs, &output)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(env.stderr, "missing OBIS code or search term")
+		fs.Usage()
+		return errUsage
+	}
+
+	term := strings.Join(fs.Args(), " ")
+
+	var codes []obis.Code
+	if c, err := obis.ParseHex(term); err == nil {
+		codes = append(codes, c)
+	} else if c, err := obis.Parse(term); err == nil {
+		codes = append(codes, c)
+	} else {
+		for _, key := range obis.Search(term) {
+			c, _ := obis.Parse(key)
+			codes = append(codes, c)
+		}
+	}
+
+	if len(codes) == 0 {
+		return fmt.Errorf("no register matches %q", term)
+	}
+
+	return writeEntries(env.stdout, output, codes)
+}
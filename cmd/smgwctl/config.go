@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/server"
+)
+
+// config is the JSON configuration file with gateway profiles and the
+// exporters of smgwctl serve
+type config struct {
+	Gateways   map[string]gatewayConfig `json:"gateways"`
+	Interval   duration                 `json:"interval"` // poll interval, default 10s
+	History    *historyConfig           `json:"history"`
+	REST       *listenerConfig          `json:"rest"`
+	Prometheus *listenerConfig          `json:"prometheus"`
+}
+
+// gatewayConfig configures the connection to a gateway
+type gatewayConfig struct {
+	URI         string `json:"uri"` // empty for mDNS discovery
+	User        string `json:"user"`
+	Password    string `json:"password"`
+	MeterID     string `json:"meter"` // empty for the first meter
+	CertFile    string `json:"cert"`  // client certificate instead of digest authentication
+	KeyFile     string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+	HostHeader  string `json:"host"`
+}
+
+// historyConfig configures the reading store
+type historyConfig struct {
+	Path      string   `json:"path"`
+	Retention duration `json:"retention"` // zero keeps all readings
+}
+
+// listenerConfig configures a built-in server
+type listenerConfig struct {
+	Listen   string   `json:"listen"` // e.g. ":8080"
+	Tokens   []string `json:"tokens"`
+	User     string   `json:"user"`
+	Password string   `json:"password"`
+	CertFile string   `json:"cert"` // serves HTTPS if set, self-signed if missing
+	KeyFile  string   `json:"key"`
+}
+
+// duration is a time.Duration encoded as string, e.g. "5s"
+type duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid duration: %s", b)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = duration(v)
+	return nil
+}
+
+// envRef matches references to environment variables starting with SMGW_
+var envRef = regexp.MustCompile(`\$(\{SMGW_\w*\}|SMGW_\w*)`)
+
+// loadConfig reads the configuration file at path. References to
+// environment variables starting with SMGW_ within strings, e.g.
+// "${SMGW_PASSWORD}", are replaced by their values, so secrets can be kept
+// out of the file. Unknown keys are errors, so typos don't silently disable
+// options.
+func loadConfig(path string) (*config, error) {
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		return nil, fmt.Errorf("%s: YAML is not supported, use JSON", path)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// values are escaped, as they are substituted into JSON strings
+	expanded := envRef.ReplaceAllStringFunc(string(b), func(ref string) string {
+		name := strings.Trim(ref, "${}")
+		quoted, _ := json.Marshal(os.Getenv(name))
+		return string(quoted[1 : len(quoted)-1])
+	})
+
+	var cfg config
+	dec := json.NewDecoder(strings.NewReader(expanded))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// client creates the gateway client
+func (c *gatewayConfig) client() (*emhcasa.Client, error) {
+	var (
+		client *emhcasa.Client
+		err    error
+	)
+
+	if c.URI == "" {
+		uri, err := emhcasa.DiscoverGatewayURI()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errUnreachable, err)
+		}
+		c.URI = uri
+	}
+
+	switch {
+	case c.CertFile != "" || c.KeyFile != "":
+		cert, certErr := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if certErr != nil {
+			return nil, fmt.Errorf("client certificate: %w", certErr)
+		}
+		client, err = emhcasa.NewClientCertificate(c.URI, cert, c.MeterID)
+
+	default:
+		client, err = emhcasa.NewClient(c.URI, c.User, c.Password, c.MeterID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Fingerprint != "" {
+		if err := client.SetCertificateFingerprint(c.Fingerprint); err != nil {
+			return nil, err
+		}
+	}
+	if c.HostHeader != "" {
+		client.SetHostHeader(c.HostHeader)
+	}
+
+	return client, nil
+}
+
+// auth returns the client authentication of the server
+func (l *listenerConfig) auth() server.Auth {
+	return server.Auth{Tokens: l.Tokens, User: l.User, Password: l.Password}
+}
+
+// tlsConfig returns the TLS configuration of the server, nil for plain HTTP
+func (l *listenerConfig) tlsConfig() (*tls.Config, error) {
+	if l.CertFile == "" && l.KeyFile == "" {
+		return nil, nil
+	}
+
+	cert, err := server.LoadOrCreateCertificate(l.CertFile, l.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/redact"
+)
+
+// Results of a diagnosis step
+const (
+	resultOK      = "ok"
+	resultFailed  = "failed"
+	resultSkipped = "skipped"
+)
+
+// diagnosis is the debug bundle written by diagnose
+type diagnosis struct {
+	Time     time.Time `json:"time"`
+	Platform string    `json:"platform"`
+	Go       string    `json:"go"`
+	Steps    []step    `json:"steps"`
+}
+
+// step is the outcome of a diagnosis step
+type step struct {
+	Name     string         `json:"name"`
+	Result   string         `json:"result"`
+	Duration string         `json:"duration"`
+	Status   int            `json:"status,omitempty"` // HTTP status code
+	Summary  string         `json:"summary,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+
+	err error // cause of a failed step
+}
+
+// run executes and records a step, returning false if it failed
+func (d *diagnosis) run(name string, fn func(s *step) error) bool {
+	s := step{Name: name, Details: map[string]any{}}
+
+	start := time.Now()
+	err := fn(&s)
+	s.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	switch {
+	case err != nil:
+		s.Result, s.Error, s.err = resultFailed, err.Error(), err
+	case s.Result == "":
+		s.Result = resultOK
+	}
+
+	d.Steps = append(d.Steps, s)
+	return err == nil
+}
+
+// redact replaces identifiers in the summaries, errors and details of all
+// steps by pseudonyms. Only string values are scrubbed, so short
+// identifiers cannot alter the structure of the bundle.
+func (d *diagnosis) redact(r *redact.Redactor, ids []string) {
+	for i := range d.Steps {
+		s := &d.Steps[i]
+		s.Summary = r.String(s.Summary, ids...)
+		s.Error = r.String(s.Error, ids...)
+		for k, v := range s.Details {
+			s.Details[k] = redactValue(r, v, ids)
+		}
+	}
+}
+
+// redactValue scrubs identifiers from strings and string slices
+func redactValue(r *redact.Redactor, v any, ids []string) any {
+	switch v := v.(type) {
+	case string:
+		return r.String(v, ids...)
+	case []string:
+		res := make([]string, len(v))
+		for i, s := range v {
+			res[i] = r.String(s, ids...)
+		}
+		return res
+	}
+	return v
+}
+
+// failed returns the first failed step
+func (d *diagnosis) failed() (step, bool) {
+	for _, s := range d.Steps {
+		if s.Result == resultFailed {
+			return s, true
+		}
+	}
+	return step{}, false
+}
+
+// diagnose checks discovery, connection, TLS handshake, authentication and
+// readings step by step and writes a redacted debug bundle
+func diagnose(ctx context.Context, env *env, args []string) error {
+	var conn connFlags
+	fs := newFlagSet("diagnose", env.stderr)
+	conn.register(fs)
+	bundle := fs.String("bundle", "smgw-diagnose.json", "path of the debug bundle")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of each step")
+	redactKey := fs.String("redact-key", os.Getenv("SMGW_REDACT_KEY"), "secret key of the pseudonyms in the debug bundle ($SMGW_REDACT_KEY)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	gw, err := conn.resolve(env)
+	if err != nil {
+		return err
+	}
+
+	d := &diagnosis{
+		Time:     time.Now().UTC().Round(time.Second),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Go:       runtime.Version(),
+	}
+
+	// identifiers to redact besides meter IDs and IP addresses
+	ids := []string{gw.User, gw.HostHeader}
+
+	var (
+		addr    string
+		tlsConf *tls.Config
+		client  *http.Client
+	)
+
+	steps := []struct {
+		name string
+		fn   func(s *step) error
+	}{
+		{"discovery", func(s *step) error {
+			if gw.URI != "" {
+				s.Result, s.Summary = resultSkipped, "uri configured"
+				return nil
+			}
+
+			uri, err := emhcasa.DiscoverGatewayURI()
+			if err != nil {
+				return fmt.Errorf("%w: %w", errUnreachable, err)
+			}
+
+			gw.URI, s.Summary = uri, uri
+			return nil
+		}},
+		{"connect", func(s *step) error {
+			u, err := url.Parse(defaultScheme(gw.URI))
+			if err != nil {
+				return fmt.Errorf("invalid gateway URI: %w", err)
+			}
+			if _, err := netip.ParseAddr(u.Hostname()); err != nil {
+				ids = append(ids, u.Hostname())
+			}
+
+			port := u.Port()
+			if port == "" {
+				port = "443"
+				if u.Scheme == "http" {
+					port = "80"
+				}
+			}
+			addr = net.JoinHostPort(u.Hostname(), port)
+
+			ctx, cancel := context.WithTimeout(ctx, *timeout)
+			defer cancel()
+
+			c, err := new(net.Dialer).DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return err
+			}
+			c.Close()
+
+			s.Summary = addr
+			if u.Scheme == "http" {
+				return nil
+			}
+
+			if tlsConf, err = gw.diagnoseTLSConfig(); err != nil {
+				return err
+			}
+			return nil
+		}},
+		{"tls", func(s *step) error {
+			if tlsConf == nil {
+				s.Result, s.Summary = resultSkipped, "plain HTTP"
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, *timeout)
+			defer cancel()
+
+			c, err := (&tls.Dialer{Config: tlsConf}).DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errUnreachable, err)
+			}
+			defer c.Close()
+
+			state := c.(*tls.Conn).ConnectionState()
+			cert := state.PeerCertificates[0]
+			ids = append(ids, cert.Subject.CommonName)
+
+			s.Summary = tls.VersionName(state.Version) + " " + tls.CipherSuiteName(state.CipherSuite)
+			s.Details["version"] = tls.VersionName(state.Version)
+			s.Details["cipher_suite"] = tls.CipherSuiteName(state.CipherSuite)
+			s.Details["fingerprint"] = fingerprint(cert.Raw)
+			s.Details["subject"] = cert.Subject.String()
+			s.Details["issuer"] = cert.Issuer.String()
+			s.Details["not_after"] = cert.NotAfter.UTC()
+			s.Details["expired"] = time.Now().After(cert.NotAfter)
+			s.Details["client_certificate"] = len(tlsConf.Certificates) > 0
+
+			if gw.Fingerprint != "" && !strings.EqualFold(strings.ReplaceAll(gw.Fingerprint, ":", ""), strings.ReplaceAll(fingerprint(cert.Raw), ":", "")) {
+				return fmt.Errorf("%w: certificate does not match the pinned fingerprint", errUnreachable)
+			}
+			return nil
+		}},
+		{"auth", func(s *step) error {
+			client = gw.diagnoseHTTPClient(tlsConf, *timeout)
+
+			var contracts []string
+			body, err := gw.fetch(ctx, client, "/json/metering/derived", s)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(body, &contracts); err != nil {
+				return fmt.Errorf("failed to unmarshal JSON: %w", err)
+			}
+
+			var meters []string
+			for _, id := range contracts {
+				ids = append(ids, id)
+
+				var contract emhcasa.DerivedContract
+				body, err := gw.fetch(ctx, client, "/json/metering/derived/"+id, nil)
+				if err != nil || json.Unmarshal(body, &contract) != nil {
+					continue
+				}
+				meters = append(meters, contract.SensorDomains...)
+			}
+			ids = append(ids, meters...)
+
+			s.Summary = fmt.Sprintf("%d contracts, %d meters", len(contracts), len(meters))
+			s.Details["contracts"] = len(contracts)
+			s.Details["meters"] = meters
+
+			if gw.MeterID == "" {
+				if len(meters) == 0 {
+					return errors.New("no contract with sensor domains found")
+				}
+				gw.MeterID = meters[0]
+			}
+			return nil
+		}},
+		{"readings", func(s *step) error {
+			body, err := gw.fetch(ctx, client, fmt.Sprintf("/json/metering/origin/%s/extended", gw.MeterID), s)
+			if err != nil {
+				return err
+			}
+
+			var reading emhcasa.MeterReading
+			if err := json.Unmarshal(body, &reading); err != nil {
+				return fmt.Errorf("failed to unmarshal JSON: %w", err)
+			}
+
+			units := map[string]int{}
+			var status, captured int
+			for _, v := range reading.Values {
+				units[v.Unit.String()]++
+				if len(v.Status) > 0 && string(v.Status) != "null" {
+					status++
+				}
+				if v.CaptureTime != "" {
+					captured++
+				}
+			}
+
+			s.Summary = fmt.Sprintf("%d values, %d bytes", len(reading.Values), len(body))
+			s.Details["bytes"] = len(body)
+			s.Details["values"] = len(reading.Values)
+			s.Details["units"] = units
+			s.Details["with_status"] = status
+			s.Details["with_capture_time"] = captured
+
+			parsed := map[string]bool{}
+			if client, err := gw.client(); err == nil {
+				readings, _ := client.GetReadingsContext(ctx)
+				for _, r := range readings {
+					parsed[r.LogicalName] = true
+				}
+			}
+
+			var unparsed []string
+			for _, v := range reading.Values {
+				if !parsed[v.LogicalName] {
+					unparsed = append(unparsed, fmt.Sprintf("%s (%s)", v.LogicalName, v.Unit))
+				}
+			}
+
+			s.Summary += fmt.Sprintf(", %d parsed", len(parsed))
+			s.Details["parsed"] = len(parsed)
+			s.Details["unparsed"] = unparsed
+
+			if len(parsed) == 0 {
+				return emhcasa.ErrNoValues
+			}
+			return nil
+		}},
+	}
+
+	for _, st := range steps {
+		if !d.run(st.name, st.fn) {
+			break
+		}
+	}
+
+	failure, failed := d.failed()
+
+	// pseudonyms can only be mapped back with a key kept by the owner,
+	// without one they are merely consistent within the bundle
+	key := []byte(*redactKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+		fmt.Fprintln(env.stderr, "no -redact-key given, pseudonyms of the debug bundle cannot be mapped back")
+	}
+	d.redact(redact.New(key), ids)
+
+	tw := tabwriter.NewWriter(env.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tRESULT\tTIME\tDETAILS")
+	for _, s := range d.Steps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Name, s.Result, s.Duration, cmp.Or(s.Error, s.Summary))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*bundle, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "\ndebug bundle written to %s\n", *bundle)
+
+	if failed {
+		return fmt.Errorf("%s: %w", failure.Name, failure.err)
+	}
+	return nil
+}
+
+// diagnoseTLSConfig returns the TLS configuration of the gateway
+// connection without certificate verification
+func (c *gatewayConfig) diagnoseTLSConfig() (*tls.Config, error) {
+	conf := &tls.Config{InsecureSkipVerify: true}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// diagnoseHTTPClient returns an HTTP client authenticating like the gateway
+// client, so status codes and payloads can be recorded
+func (c *gatewayConfig) diagnoseHTTPClient(tlsConf *tls.Config, timeout time.Duration) *http.Client {
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConf,
+	}
+
+	if c.CertFile == "" && c.KeyFile == "" {
+		transport = emhcasa.NewDigestTransport(c.User, c.Password, transport)
+	}
+
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// fetch returns the response body of path, recording the status code in s
+// if not nil
+func (c *gatewayConfig) fetch(ctx context.Context, client *http.Client, path string, s *step) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(defaultScheme(c.URI), "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.HostHeader != "" {
+		req.Host = c.HostHeader
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if s != nil {
+		s.Status = resp.StatusCode
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("authentication failed: %w", &emhcasa.StatusError{Code: resp.StatusCode})
+	}
+
+	return nil, &emhcasa.StatusError{Code: resp.StatusCode}
+}
+
+// fingerprint returns the SHA-256 fingerprint of a certificate as
+// colon-separated hex
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// defaultScheme adds https:// to URIs without scheme
+func defaultScheme(uri string) string {
+	if !strings.HasPrefix(uri, "http://") && !strings.HasPrefix(uri, "https://") {
+		return "https://" + uri
+	}
+	return uri
+}
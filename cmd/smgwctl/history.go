@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iseeberg79/emh-casa-go/exporter/csv"
+	"github.com/iseeberg79/emh-casa-go/store"
+)
+
+// history exports stored series of the reading history recorded by serve
+func history(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("history", env.stderr)
+	path := fs.String("store", "", "reading history file (default: history path of the configuration file)")
+	configPath := fs.String("config", os.Getenv("SMGW_CONFIG"), "configuration file of serve ($SMGW_CONFIG)")
+	codes := fs.String("obis", "1.8.0", "comma-separated OBIS codes to export")
+	from := fs.String("from", "", "start as date (2006-01-02) or RFC 3339 time (default: 24h before -to)")
+	to := fs.String("to", "", "end (exclusive) as date or RFC 3339 time (default: now)")
+	step := fs.Duration("step", 0, "average values within windows of this size, e.g. 15m (default: stored values)")
+	deltas := fs.Bool("deltas", false, "print counter increases, e.g. the energy per -step")
+	var output format
+	registerFormat(fs, &output)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	end, err := parseTime(*to, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+	start, err := parseTime(*from, end.Add(-24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+
+	if *path == "" && *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if cfg.History != nil {
+			*path = cfg.History.Path
+		}
+	}
+	if *path == "" {
+		return errors.New("no reading history: use -store or a configuration file with history")
+	}
+
+	st, err := store.OpenReadOnly(*path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var (
+		series []store.Series
+		values int
+	)
+	for _, code := range strings.Split(*codes, ",") {
+		s, err := st.Series(strings.TrimSpace(code), start, end)
+		if err != nil {
+			return err
+		}
+
+		if *step > 0 {
+			s = s.Resample(*step)
+		}
+		if *deltas {
+			s = s.Deltas()
+		}
+
+		series = append(series, s)
+		values += len(s.Values)
+	}
+
+	if values == 0 {
+		return fmt.Errorf("%w between %s and %s", errNoReadings, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	return writeSeries(env.stdout, output, series)
+}
+
+// parseTime parses a date in local time or an RFC 3339 time, returning def
+// if s is empty
+func parseTime(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// writeSeries writes stored series in the selected format
+func writeSeries(w io.Writer, f format, series []store.Series) error {
+	switch f {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(series)
+
+	case formatCSV:
+		return csv.WriteSeries(w, series...)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tOBIS\tVALUE\tUNIT")
+	for _, s := range series {
+		for _, v := range s.Values {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", v.Time.Local().Format(time.DateTime), s.OBIS, strconv.FormatFloat(v.Value, 'f', -1, 64), s.Unit)
+		}
+	}
+	return tw.Flush()
+}
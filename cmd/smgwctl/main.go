@@ -0,0 +1,228 @@
+// Command smgwctl reads a CASA 1.1 smart meter gateway from the command line.
+//
+// Usage:
+//
+//	smgwctl discover [-timeout 2s]
+//	smgwctl read     [connection flags] [-obis pattern,...] [-quiet]
+//	smgwctl meters   [connection flags]
+//	smgwctl watch    [connection flags] [-obis pattern,...] [-interval 5s] [-quiet]
+//	smgwctl history  [-config smgw.json] [-obis 1.8.0] [-from 2026-01-01] [-to 2026-02-01] [-step 15m] [-deltas]
+//	smgwctl tunnel   -via user@host <read|meters|watch|diagnose> [flags]
+//	smgwctl diagnose [connection flags] [-bundle smgw-diagnose.json]
+//	smgwctl probe    [connection flags] [-warning 5s] [-critical 10s] [-max-age 15m]
+//	smgwctl obis     [-output table] <code|search-term>
+//	smgwctl serve    [-config smgw.json]
+//
+// The gateway is discovered via mDNS unless -uri is given. It is accessed
+// with digest authentication (-user, -password) or a TLS client certificate
+// (-cert, -key). Connection settings may also be taken from SMGW_*
+// environment variables or a gateway profile of the configuration file
+// (-config, -gateway). read, meters and watch print a table, JSON or CSV
+// (-output). history exports the readings recorded by serve. tunnel runs
+// read, meters, watch and diagnose through an SSH port forward. diagnose
+// checks the connection step by step and writes a redacted debug bundle for
+// bug reports. probe checks the gateway once as Nagios/Icinga plugin. obis
+// prints the description, unit and CASA logical names of matching
+// registers, for debugging unknown readings. serve runs as a daemon polling
+// the gateways of a JSON configuration file and serving the REST API and
+// Prometheus metrics:
+//
+//	{
+//	  "interval": "10s",
+//	  "gateways": {"home": {"uri": "https://192.168.33.2", "user": "admin", "password": "secret"}},
+//	  "history": {"path": "readings.jsonl", "retention": "720h"},
+//	  "rest": {"listen": ":8080", "tokens": ["secret"], "cert": "cert.pem", "key": "key.pem"},
+//	  "prometheus": {"listen": ":9100"}
+//	}
+//
+// Run "smgwctl <command> -h" for all flags. read and watch print only the
+// values, one per line, with -quiet. The exit code tells scripts why a
+// command failed, except for probe, which exits with the plugin state:
+//
+//	0   success
+//	1   other error
+//	2   authentication failed
+//	3   gateway unreachable (discovery, network or TLS failure)
+//	4   no readings
+//	64  invalid usage
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/daemon"
+)
+
+// command is a subcommand
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, env *env, args []string) error
+}
+
+// env holds the streams of an invocation
+type env struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	// forward rewrites the resolved connection settings to go through a
+	// tunnel, if set
+	forward func(gw *gatewayConfig) error
+}
+
+// commands are the subcommands in usage order
+var commands = []command{
+	{"discover", "find the gateway via mDNS and print its URI", discover},
+	{"read", "print the current readings", read},
+	{"meters", "list the metering contracts and meter IDs", meters},
+	{"watch", "print readings continuously until interrupted", watch},
+	{"history", "export series of the reading history recorded by serve", history},
+	{"tunnel", "run read, meters, watch or diagnose through an SSH port forward", tunnel},
+	{"diagnose", "check the gateway connection and write a debug bundle", diagnose},
+	{"probe", "check the gateway once with Nagios plugin output", probe},
+	{"obis", "describe an OBIS code or search the registry", lookup},
+	{"serve", "poll gateways and run the exporters of a config file", serve},
+}
+
+func main() {
+	ctx, cancel := daemon.SignalContext(context.Background())
+	defer cancel()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return exitUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		err := cmd.run(ctx, &env{stdout: stdout, stderr: stderr}, args[1:])
+
+		var status exitStatus
+		code := exitCode(err)
+		if code != exitOK && code != exitUsage && !errors.As(err, &status) {
+			fmt.Fprintf(stderr, "smgwctl %s: %v\n", cmd.name, err)
+		}
+		return code
+	}
+
+	fmt.Fprintf(stderr, "smgwctl: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+// Exit codes, see the package documentation
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitAuth        = 2
+	exitUnreachable = 3
+	exitNoReadings  = 4
+	exitUsage       = 64
+)
+
+var (
+	// errUsage is returned for invalid flags, after printing the flag usage
+	errUsage = errors.New("invalid usage")
+
+	// errUnreachable wraps errors of gateway discovery
+	errUnreachable = errors.New("gateway unreachable")
+
+	// errNoReadings is returned if no reading matches the selected codes
+	errNoReadings = errors.New("no readings")
+)
+
+// exitStatus is returned by commands that report their result in the exit
+// code, e.g. probe, after printing their output
+type exitStatus int
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// exitCode returns the exit code of a command failing with err
+func exitCode(err error) int {
+	var (
+		status    exitStatus
+		statusErr *emhcasa.StatusError
+		urlErr    *url.Error
+		opErr     *net.OpError
+	)
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &status):
+		return int(status)
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.As(err, &statusErr) && (statusErr.Code == http.StatusUnauthorized || statusErr.Code == http.StatusForbidden):
+		return exitAuth
+	case errors.Is(err, errNoReadings), errors.Is(err, emhcasa.ErrNoValues):
+		return exitNoReadings
+	case errors.Is(err, errUnreachable), errors.As(err, &urlErr), errors.As(err, &opErr):
+		return exitUnreachable
+	}
+
+	return exitError
+}
+
+// usage prints the available commands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: smgwctl <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// newFlagSet creates the flag set of a command printing errors to stderr
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("smgwctl "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parse parses the flags of a command without positional arguments
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %v\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
+
+// parseArgs parses the flags of a command followed by positional arguments,
+// mapping flag errors to errUsage
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/redact"
+	"github.com/iseeberg79/emh-casa-go/store"
+)
+
+// gatewayServer returns a fake CASA gateway with one meter
+func gatewayServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json/metering/derived":
+			fmt.Fprint(w, `["c1"]`)
+		case "/json/metering/derived/c1":
+			fmt.Fprint(w, `{"taf_type":"TAF-1","state":"active","sensor_domains":["1EMH0012345678"]}`)
+		case "/json/metering/origin/1EMH0012345678/extended":
+			fmt.Fprint(w, `{"values":[
+				{"value":"12345678","unit":30,"scaler":-1,"logical_name":"0100010800FF.255"},
+				{"value":"500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// execute runs the command line and returns exit code, stdout and stderr
+func execute(ctx context.Context, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(ctx, args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestRun tests the commands against a fake gateway
+func TestRun(t *testing.T) {
+	srv := gatewayServer(t)
+	conn := []string{"-uri", srv.URL, "-user", "admin", "-password", "secret"}
+
+	code, out, errOut := execute(context.Background(), append([]string{"read"}, conn...)...)
+	want := "OBIS    DESCRIPTION             VALUE      UNIT\n" +
+		"1.8.0   Total Energy Import     1234.5678  kWh\n" +
+		"16.7.0  Current Power (Active)  500        W\n"
+	if code != 0 || out != want {
+		t.Errorf("read = %d %q %q, want %q", code, out, errOut, want)
+	}
+
+	if code, out, _ := execute(context.Background(), append([]string{"read", "-obis", "16.*.*"}, conn...)...); code != 0 || strings.Contains(out, "1.8.0") || !strings.Contains(out, "16.7.0") {
+		t.Errorf("read -obis = %d %q", code, out)
+	}
+
+	if code, out, _ := execute(context.Background(), append([]string{"meters"}, conn...)...); code != 0 ||
+		out != "CONTRACT  TAF    STATE   METERS\nc1        TAF-1  active  1EMH0012345678\n" {
+		t.Errorf("meters = %d %q", code, out)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if code, out, _ := execute(ctx, append([]string{"watch", "-interval", "10ms", "-obis", "16.7.0"}, conn...)...); code != 0 || strings.Count(out, "500") < 2 {
+		t.Errorf("watch = %d %q", code, out)
+	}
+}
+
+// TestOutput tests the JSON and CSV output formats
+func TestOutput(t *testing.T) {
+	srv := gatewayServer(t)
+	conn := []string{"-uri", srv.URL, "-user", "admin", "-password", "secret"}
+
+	_, out, _ := execute(context.Background(), append([]string{"read", "-output", "json", "-obis", "1.8.0"}, conn...)...)
+	var readings []emhcasa.Reading
+	if err := json.Unmarshal([]byte(out), &readings); err != nil || len(readings) != 1 || readings[0].RawValue != "12345678" {
+		t.Errorf("read -output json = %q, %v", out, err)
+	}
+
+	_, out, _ = execute(context.Background(), append([]string{"read", "-output", "csv"}, conn...)...)
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || lines[0] != "time,obis,description,value,unit,raw_value,scaler,quality" ||
+		!strings.Contains(lines[2], ",16.7.0,Current Power (Active),500,W,500,0,good") {
+		t.Errorf("read -output csv = %q", out)
+	}
+
+	_, out, _ = execute(context.Background(), append([]string{"meters", "-output", "json"}, conn...)...)
+	if !strings.Contains(out, `"id": "c1"`) || !strings.Contains(out, `"state": "active"`) || !strings.Contains(out, `"1EMH0012345678"`) {
+		t.Errorf("meters -output json = %q", out)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, out, _ = execute(ctx, append([]string{"watch", "-interval", "10ms", "-output", "csv"}, conn...)...)
+	if strings.Count(out, "time,obis") != 1 || strings.Count(out, ",16.7.0,") < 2 {
+		t.Errorf("watch -output csv = %q", out)
+	}
+
+	if code, _, errOut := execute(context.Background(), "read", "-output", "xml"); code != 64 || !strings.Contains(errOut, "unknown format") {
+		t.Errorf("read -output xml = %d %q", code, errOut)
+	}
+}
+
+// TestUsage tests exit codes of invalid command lines
+func TestUsage(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, 64},
+		{[]string{"unknown"}, 64},
+		{[]string{"read", "-unknown"}, 64},
+		{[]string{"read", "extra"}, 64},
+		{[]string{"read", "-h"}, 0},
+		{[]string{"read", "-uri", "https://127.0.0.1:1", "-user", "admin", "-password", "secret"}, 3},
+		{[]string{"obis"}, 64},
+		{[]string{"obis", "nothing"}, 1},
+	}
+
+	for _, tt := range tests {
+		if code, _, _ := execute(context.Background(), tt.args...); code != tt.want {
+			t.Errorf("%v = %d, want %d", tt.args, code, tt.want)
+		}
+	}
+}
+
+// TestExitCodes tests exit codes of failing gateway requests and quiet output
+func TestExitCodes(t *testing.T) {
+	srv := gatewayServer(t)
+	conn := []string{"-uri", srv.URL, "-user", "admin", "-password", "secret"}
+
+	if code, out, _ := execute(context.Background(), append([]string{"read", "-quiet", "-obis", "16.7.0"}, conn...)...); code != 0 || out != "500\n" {
+		t.Errorf("read -quiet = %d %q", code, out)
+	}
+	if code, out, _ := execute(context.Background(), append([]string{"read", "-quiet", "-obis", "2.8.0"}, conn...)...); code != 4 || out != "" {
+		t.Errorf("read -obis 2.8.0 = %d %q", code, out)
+	}
+
+	denied := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(denied.Close)
+
+	if code, _, errOut := execute(context.Background(), "read", "-uri", denied.URL, "-user", "admin", "-password", "wrong", "-meter", "1EMH0012345678"); code != 2 || !strings.Contains(errOut, "401") {
+		t.Errorf("read unauthorized = %d %q", code, errOut)
+	}
+}
+
+// TestLookup tests the obis command with codes, logical names and search terms
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"1-0:1.8.0*255"}, "OBIS   DESCRIPTION          UNIT  HEX\n1.8.0  Total Energy Import  kWh   0100010800FF\n"},
+		{[]string{"0100100700FF"}, "OBIS    DESCRIPTION             UNIT  HEX\n16.7.0  Current Power (Active)  W     0100100700FF\n"},
+		{[]string{"99.1.1"}, "OBIS    DESCRIPTION  UNIT  HEX\n99.1.1  unknown            0100630101FF\n"},
+		{[]string{"grid", "frequency"}, "OBIS    DESCRIPTION     UNIT  HEX\n14.7.0  Grid Frequency  Hz    01000E0700FF\n"},
+		{[]string{"-output", "csv", "gas"}, "obis,description,unit,hex\n7-0:3.0.0,Gas Volume,m³,0700030000FF\n"},
+	}
+
+	for _, tt := range tests {
+		code, out, errOut := execute(context.Background(), append([]string{"obis"}, tt.args...)...)
+		if code != 0 || out != tt.want {
+			t.Errorf("obis %v = %d %q %q, want %q", tt.args, code, out, errOut, tt.want)
+		}
+	}
+}
+
+// TestProfiles tests gateway profiles, environment variables and flag precedence
+func TestProfiles(t *testing.T) {
+	srv := gatewayServer(t)
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "smgw.json")
+	cfg := fmt.Sprintf(`{"gateways": {
+		"home": {"uri": %q, "user": "admin", "password": "${SMGW_TEST_PASSWORD}"},
+		"garage": {"uri": "https://127.0.0.1:1", "user": "admin", "password": "pa$$w${or}d"}
+	}}`, srv.URL)
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SMGW_TEST_PASSWORD", `se"c\ret`)
+	t.Setenv("SMGW_CONFIG", path)
+
+	loaded, err := loadConfig(path)
+	if err != nil || loaded.Gateways["home"].Password != `se"c\ret` || loaded.Gateways["garage"].Password != "pa$$w${or}d" {
+		t.Errorf("loadConfig() = %+v, %v", loaded, err)
+	}
+	t.Setenv("SMGW_TEST_PASSWORD", "secret")
+
+	if code, _, errOut := execute(context.Background(), "read"); code != 1 || !strings.Contains(errOut, "select a gateway with -gateway: garage, home") {
+		t.Errorf("read without profile = %d %q", code, errOut)
+	}
+	if code, out, errOut := execute(context.Background(), "read", "-gateway", "home"); code != 0 || !strings.Contains(out, "16.7.0") {
+		t.Errorf("read -gateway home = %d %q %q", code, out, errOut)
+	}
+
+	// environment overrides the profile, flags override the environment
+	t.Setenv("SMGW_GATEWAY", "garage")
+	t.Setenv("SMGW_URI", srv.URL)
+	if code, _, errOut := execute(context.Background(), "meters"); code != 0 {
+		t.Errorf("meters with SMGW_URI = %d %q", code, errOut)
+	}
+	if code, _, _ := execute(context.Background(), "meters", "-uri", "https://127.0.0.1:1"); code != 3 {
+		t.Errorf("meters -uri = %d, want flag to take precedence", code)
+	}
+
+	if code, _, errOut := execute(context.Background(), "read", "-gateway", "other"); code != 1 || !strings.Contains(errOut, "unknown gateway") {
+		t.Errorf("read -gateway other = %d %q", code, errOut)
+	}
+	if code, _, errOut := execute(context.Background(), "read", "-config", filepath.Join(dir, "smgw.yaml")); code != 1 || !strings.Contains(errOut, "YAML") {
+		t.Errorf("read -config smgw.yaml = %d %q", code, errOut)
+	}
+}
+
+// TestDiagnose tests the diagnosis steps and the redacted debug bundle
+func TestDiagnose(t *testing.T) {
+	srv := gatewayServer(t)
+	bundle := filepath.Join(t.TempDir(), "bundle.json")
+
+	// a user name matching a JSON key must not alter the bundle
+	code, out, errOut := execute(context.Background(), "diagnose", "-uri", srv.URL, "-user", "steps", "-password", "secret", "-bundle", bundle,
+		"-redact-key", "my-secret")
+	if code != 0 {
+		t.Fatalf("diagnose = %d %q %q", code, out, errOut)
+	}
+	for _, want := range []string{"discovery  skipped", "tls        ok", "1 contracts, 1 meters", "2 values"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("diagnose output %q misses %q", out, want)
+		}
+	}
+
+	b, err := os.ReadFile(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"1EMH0012345678", "127.0.0.1", "secret"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("bundle contains %q", secret)
+		}
+	}
+
+	var d diagnosis
+	if err := json.Unmarshal(b, &d); err != nil || len(d.Steps) != 5 || d.Steps[3].Status != 200 {
+		t.Errorf("bundle = %+v, %v", d, err)
+	}
+
+	// the owner of the key can map pseudonyms back
+	if meter := redact.New([]byte("my-secret")).Pseudonym("id", "1EMH0012345678"); !strings.Contains(string(b), meter) {
+		t.Errorf("bundle misses pseudonym %s", meter)
+	}
+
+	// unreachable gateway
+	code, out, _ = execute(context.Background(), "diagnose", "-uri", "https://127.0.0.1:1", "-bundle", bundle)
+	if code != 3 || !strings.Contains(out, "connect    failed") {
+		t.Errorf("diagnose unreachable = %d %q", code, out)
+	}
+}
+
+// TestHistory tests exporting stored series as 15 minute energy profile
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "readings.jsonl")
+
+	st, err := store.Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range []float64{100, 100.5, 101, 102} {
+		r := emhcasa.Reading{OBIS: "1.8.0", Value: v, Unit: emhcasa.UnitWattHour, Timestamp: start.Add(time.Duration(i) * 15 * time.Minute)}
+		if err := st.Add([]emhcasa.Reading{r}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st.Close()
+
+	code, out, errOut := execute(context.Background(), "history", "-store", path, "-from", "2026-01-01T00:00:00Z", "-to", "2026-01-02T00:00:00Z",
+		"-step", "15m", "-deltas", "-output", "csv")
+	want := "time,1.8.0 (kWh)\n2026-01-01T00:15:00Z,0.5\n2026-01-01T00:30:00Z,0.5\n2026-01-01T00:45:00Z,1\n"
+	if code != 0 || out != want {
+		t.Errorf("history = %d %q %q, want %q", code, out, errOut, want)
+	}
+
+	// history path of the configuration file
+	cfg := filepath.Join(dir, "smgw.json")
+	if err := os.WriteFile(cfg, []byte(fmt.Sprintf(`{"history": {"path": %q}}`, path)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code, out, _ := execute(context.Background(), "history", "-config", cfg, "-from", "2026-01-01T00:00:00Z", "-to", "2026-01-02T00:00:00Z", "-output", "json"); code != 0 || strings.Count(out, `"value"`) != 4 {
+		t.Errorf("history -config = %d %q", code, out)
+	}
+
+	if code, _, _ := execute(context.Background(), "history", "-store", path, "-from", "2025-01-01", "-to", "2025-01-02"); code != 4 {
+		t.Errorf("history without values = %d, want 4", code)
+	}
+	if code, _, _ := execute(context.Background(), "history", "-store", filepath.Join(dir, "missing.jsonl")); code != 1 {
+		t.Errorf("history without store = %d, want 1", code)
+	}
+}
+
+// TestProbe tests plugin states, output and perfdata of probe
+func TestProbe(t *testing.T) {
+	srv := gatewayServer(t)
+	conn := []string{"-uri", srv.URL, "-user", "admin", "-password", "secret"}
+
+	code, out, _ := execute(context.Background(), append([]string{"probe"}, conn...)...)
+	if code != 0 || !strings.HasPrefix(out, "SMGW OK - total energy import 1234.5678 kWh, current power (active) 500 W | time=") ||
+		!strings.HasSuffix(out, "s;5.000;10.000;0 '1.8.0'=1234.5678kWh '16.7.0'=500W\n") {
+		t.Errorf("probe = %d %q", code, out)
+	}
+
+	if code, out, _ := execute(context.Background(), append([]string{"probe", "-warning", "1ns", "-obis", "16.7.0"}, conn...)...); code != 1 ||
+		!strings.HasPrefix(out, "SMGW WARNING - response time") || strings.Contains(out, "1.8.0") {
+		t.Errorf("probe -warning = %d %q", code, out)
+	}
+
+	if code, out, errOut := execute(context.Background(), "probe", "-uri", "https://127.0.0.1:1", "-user", "admin", "-password", "secret"); code != 2 ||
+		!strings.HasPrefix(out, "SMGW CRITICAL - ") || errOut != "" {
+		t.Errorf("probe unreachable = %d %q %q", code, out, errOut)
+	}
+
+	if code, out, _ := execute(context.Background(), "probe", "-uri", srv.URL); code != 3 || !strings.HasPrefix(out, "SMGW UNKNOWN - ") {
+		t.Errorf("probe without credentials = %d %q", code, out)
+	}
+}
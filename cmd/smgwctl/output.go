@@ -0,0 +1,202 @@
+package main
+
+import (
+	"cmp"
+	stdcsv "encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/exporter/csv"
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// format is the value of the -output flag
+type format string
+
+// Output formats
+const (
+	formatTable format = "table" // aligned columns with registry descriptions
+	formatJSON  format = "json"  // JSON encoding of emhcasa.Reading
+	formatCSV   format = "csv"   // long format of exporter/csv
+)
+
+// String implements flag.Value.
+func (f *format) String() string {
+	return string(*f)
+}
+
+// Set implements flag.Value.
+func (f *format) Set(s string) error {
+	switch format(s) {
+	case formatTable, formatJSON, formatCSV:
+		*f = format(s)
+		return nil
+	}
+	return fmt.Errorf("unknown format %q, want table, json or csv", s)
+}
+
+// registerFormat adds the -output flag to fs
+func registerFormat(fs *flag.FlagSet, f *format) {
+	*f = formatTable
+	fs.Var(f, "output", "output format: table, json or csv")
+}
+
+// readingsOutput writes readings in the selected format. Multiple writes,
+// e.g. from watch, produce one table or JSON document per poll and CSV rows
+// with a single header.
+type readingsOutput struct {
+	format format
+	w      io.Writer
+	csv    *csv.Writer
+	stream bool // write polls of watch, separated by time in table format
+	quiet  bool // write values only, one per line
+}
+
+// newReadingsOutput creates a readingsOutput writing to w
+func newReadingsOutput(f format, w io.Writer, stream bool) *readingsOutput {
+	return &readingsOutput{format: f, w: w, csv: csv.NewWriter(w), stream: stream}
+}
+
+// write writes the readings of a poll finished at t
+func (o *readingsOutput) write(t time.Time, readings []emhcasa.Reading) error {
+	if readings == nil {
+		readings = []emhcasa.Reading{}
+	}
+
+	switch {
+	case o.quiet:
+		for _, r := range readings {
+			fmt.Fprintln(o.w, strconv.FormatFloat(r.Value, 'f', -1, 64))
+		}
+		return nil
+
+	case o.format == formatJSON:
+		enc := json.NewEncoder(o.w)
+		if !o.stream {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(readings)
+
+	case o.format == formatCSV:
+		return o.csv.Write(readings)
+	}
+
+	if o.stream {
+		fmt.Fprintln(o.w, t.Format(time.TimeOnly))
+	}
+	if err := writeTable(o.w, readings); err != nil {
+		return err
+	}
+	if o.stream {
+		fmt.Fprintln(o.w)
+	}
+	return nil
+}
+
+// writeTable writes readings as aligned table with registry descriptions
+func writeTable(w io.Writer, readings []emhcasa.Reading) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OBIS\tDESCRIPTION\tVALUE\tUNIT")
+
+	for _, r := range readings {
+		value := strconv.FormatFloat(r.Value, 'f', -1, 64)
+		if r.Quality != emhcasa.QualityGood {
+			value += " (" + r.Quality.String() + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.OBIS, obis.Description(r.OBIS), value, r.ValueUnit())
+	}
+
+	return tw.Flush()
+}
+
+// contract is the JSON encoding of a metering contract
+type contract struct {
+	ID            string   `json:"id"`
+	TafType       string   `json:"taf_type"`
+	State         string   `json:"state,omitempty"`
+	SensorDomains []string `json:"sensor_domains"`
+}
+
+// writeContracts writes metering contracts in the selected format
+func writeContracts(w io.Writer, f format, contracts []emhcasa.DerivedContract) error {
+	list := make([]contract, 0, len(contracts))
+	for _, c := range contracts {
+		list = append(list, contract{ID: c.ID, TafType: c.TafType, State: c.State, SensorDomains: c.SensorDomains})
+	}
+
+	switch f {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+
+	case formatCSV:
+		cw := stdcsv.NewWriter(w)
+		_ = cw.Write([]string{"contract", "taf", "state", "meters"})
+		for _, c := range list {
+			_ = cw.Write([]string{c.ID, c.TafType, c.State, strings.Join(c.SensorDomains, " ")})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONTRACT\tTAF\tSTATE\tMETERS")
+	for _, c := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.ID, c.TafType, cmp.Or(c.State, "-"), strings.Join(c.SensorDomains, ","))
+	}
+	return tw.Flush()
+}
+
+// entry is the JSON encoding of a registry entry
+type entry struct {
+	Code        string   `json:"code"`
+	Description string   `json:"description"`
+	Unit        string   `json:"unit"`
+	Hex         []string `json:"hex"`
+}
+
+// writeEntries writes the registry entries of codes in the selected format.
+// Hex lists the standard logical name followed by registered vendor names.
+func writeEntries(w io.Writer, f format, codes []obis.Code) error {
+	list := make([]entry, 0, len(codes))
+	for _, c := range codes {
+		e, _ := obis.Lookup(c.String())
+		list = append(list, entry{
+			Code:        c.Key(),
+			Description: e.Description,
+			Unit:        e.Unit,
+			Hex:         append([]string{c.Hex()}, obis.HexNames(c)...),
+		})
+	}
+
+	switch f {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+
+	case formatCSV:
+		cw := stdcsv.NewWriter(w)
+		_ = cw.Write([]string{"obis", "description", "unit", "hex"})
+		for _, e := range list {
+			_ = cw.Write([]string{e.Code, e.Description, e.Unit, strings.Join(e.Hex, " ")})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OBIS\tDESCRIPTION\tUNIT\tHEX")
+	for _, e := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Code, cmp.Or(e.Description, "unknown"), e.Unit, strings.Join(e.Hex, ","))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// Monitoring plugin states, which are also the exit codes of probe
+const (
+	probeOK       exitStatus = 0
+	probeWarning  exitStatus = 1
+	probeCritical exitStatus = 2
+	probeUnknown  exitStatus = 3
+)
+
+// probeStates are the names of the plugin states
+var probeStates = map[exitStatus]string{
+	probeOK:       "OK",
+	probeWarning:  "WARNING",
+	probeCritical: "CRITICAL",
+	probeUnknown:  "UNKNOWN",
+}
+
+// probe performs a single check of the gateway for Nagios and Icinga
+func probe(ctx context.Context, env *env, args []string) error {
+	var conn connFlags
+	fs := newFlagSet("probe", env.stderr)
+	conn.register(fs)
+	warning := fs.Duration("warning", 5*time.Second, "response time resulting in WARNING")
+	critical := fs.Duration("critical", 10*time.Second, "response time resulting in CRITICAL, also the request timeout")
+	maxAge := fs.Duration("max-age", 15*time.Minute, "age of the newest reading resulting in WARNING")
+	patterns := fs.String("obis", "", "comma-separated OBIS patterns of the perfdata (default: active power and energy)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	gw, err := conn.resolve(env)
+	if err != nil {
+		return report(env, probeUnknown, err.Error(), "")
+	}
+
+	client, err := gw.client()
+	if err != nil {
+		return report(env, probeUnknown, err.Error(), "")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *critical)
+	defer cancel()
+
+	start := time.Now()
+	readings, err := client.GetReadingsContext(ctx)
+	elapsed := time.Since(start)
+
+	perfdata := fmt.Sprintf("time=%ss;%s;%s;0", seconds(elapsed), seconds(*warning), seconds(*critical))
+	if err != nil {
+		return report(env, probeCritical, err.Error(), perfdata)
+	}
+
+	var (
+		selected []emhcasa.Reading
+		summary  []string
+		invalid  int
+		newest   time.Time
+	)
+	for _, r := range readings {
+		if r.Quality == emhcasa.QualityInvalid {
+			invalid++
+		}
+		if r.Timestamp.After(newest) {
+			newest = r.Timestamp
+		}
+
+		e, _ := obis.Lookup(r.OBIS)
+		switch {
+		case *patterns != "":
+			if obis.Filter(strings.Split(*patterns, ",")).Match(r.OBIS) {
+				selected = append(selected, r)
+			}
+		case e.Quantity == obis.QuantityActivePower || e.Quantity == obis.QuantityActiveEnergy:
+			selected = append(selected, r)
+		}
+
+		switch r.OBIS {
+		case obis.Power, obis.EnergyImport, obis.EnergyExport:
+			summary = append(summary, fmt.Sprintf("%s %s %s", strings.ToLower(obis.Description(r.OBIS)), formatValue(r.Value), r.ValueUnit()))
+		}
+	}
+
+	for _, r := range selected {
+		perfdata += fmt.Sprintf(" '%s'=%s%s", r.OBIS, formatValue(r.Value), r.ValueUnit())
+	}
+
+	msg := cmp.Or(strings.Join(summary, ", "), fmt.Sprintf("%d readings", len(readings)))
+	switch {
+	case elapsed >= *critical:
+		return report(env, probeCritical, fmt.Sprintf("response time %s, %s", elapsed.Round(time.Millisecond), msg), perfdata)
+	case elapsed >= *warning:
+		return report(env, probeWarning, fmt.Sprintf("response time %s, %s", elapsed.Round(time.Millisecond), msg), perfdata)
+	case invalid > 0:
+		return report(env, probeWarning, fmt.Sprintf("%d readings of invalid quality, %s", invalid, msg), perfdata)
+	case time.Since(newest) > *maxAge:
+		return report(env, probeWarning, fmt.Sprintf("newest reading is %s old, %s", time.Since(newest).Round(time.Second), msg), perfdata)
+	}
+
+	return report(env, probeOK, msg, perfdata)
+}
+
+// report prints the plugin output line and returns the state as exit status,
+// nil for OK
+func report(env *env, state exitStatus, msg, perfdata string) error {
+	line := fmt.Sprintf("SMGW %s - %s", probeStates[state], msg)
+	if perfdata != "" {
+		line += " | " + perfdata
+	}
+	fmt.Fprintln(env.stdout, line)
+
+	if state == probeOK {
+		return nil
+	}
+	return state
+}
+
+// seconds formats d in seconds with millisecond precision
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// formatValue formats v with the minimum number of digits
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
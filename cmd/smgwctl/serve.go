@@ -0,0 +1,214 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/daemon"
+	"github.com/iseeberg79/emh-casa-go/exporter/prometheus"
+	"github.com/iseeberg79/emh-casa-go/poll"
+	"github.com/iseeberg79/emh-casa-go/server"
+	"github.com/iseeberg79/emh-casa-go/server/rest"
+	"github.com/iseeberg79/emh-casa-go/store"
+)
+
+// defaultInterval is the poll interval of serve if not configured
+const defaultInterval = 10 * time.Second
+
+// latest is a Gateway returning the result of the last poll, so exporters
+// don't read the gateway on every request
+type latest struct {
+	client *emhcasa.Client
+
+	mu       sync.Mutex
+	readings []emhcasa.Reading
+	err      error
+}
+
+// set stores the result of a poll
+func (l *latest) set(readings []emhcasa.Reading, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.readings, l.err = readings, err
+}
+
+// GetReadings implements emhcasa.Gateway.
+func (l *latest) GetReadings() ([]emhcasa.Reading, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.readings == nil && l.err == nil {
+		return nil, errors.New("no readings yet")
+	}
+	return l.readings, l.err
+}
+
+// MeterID returns the meter ID of the client, for exporter labels.
+func (l *latest) MeterID() (string, error) {
+	return l.client.MeterID()
+}
+
+// serve polls the configured gateways and runs the configured exporters
+// until ctx is cancelled
+func serve(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("serve", env.stderr)
+	path := fs.String("config", cmp.Or(os.Getenv("SMGW_CONFIG"), "smgw.json"), "configuration file ($SMGW_CONFIG)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*path)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Gateways) == 0 {
+		return errors.New("no gateways configured")
+	}
+	if cfg.REST == nil && cfg.Prometheus == nil && cfg.History == nil {
+		return errors.New("no rest, prometheus or history configured")
+	}
+
+	interval := cmp.Or(time.Duration(cfg.Interval), defaultInterval)
+	names := slices.Sorted(maps.Keys(cfg.Gateways))
+
+	var st *store.Store
+	if cfg.History != nil {
+		if st, err = store.Open(cfg.History.Path, time.Duration(cfg.History.Retention)); err != nil {
+			return err
+		}
+		defer st.Close()
+	}
+
+	gateways := make(map[string]emhcasa.Gateway, len(names))
+	caches := make(map[string]*latest, len(names))
+	for _, name := range names {
+		gw := cfg.Gateways[name]
+		client, err := gw.client()
+		if err != nil {
+			return fmt.Errorf("gateway %s: %w", name, err)
+		}
+
+		caches[name] = &latest{client: client}
+		gateways[name] = caches[name]
+	}
+
+	health := server.NewHealth(3*interval, names...)
+
+	var api *rest.Server
+	if cfg.REST != nil {
+		api = rest.New(gateways, st)
+		api.SetHealth(health)
+		api.SetAuth(cfg.REST.auth())
+
+		tlsConfig, err := cfg.REST.tlsConfig()
+		if err != nil {
+			return fmt.Errorf("rest: %w", err)
+		}
+		api.SetTLSConfig(tlsConfig)
+	}
+
+	var exporter *prometheus.Exporter
+	if cfg.Prometheus != nil {
+		exporter = prometheus.New(gateways)
+		exporter.SetAuth(cfg.Prometheus.auth())
+
+		tlsConfig, err := cfg.Prometheus.tlsConfig()
+		if err != nil {
+			return fmt.Errorf("prometheus: %w", err)
+		}
+		exporter.SetTLSConfig(tlsConfig)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		errc = make(chan error, len(names)+2)
+	)
+
+	// start runs f until ctx is cancelled; failures stop all other tasks
+	start := func(name string, f func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				errc <- fmt.Errorf("%s: %w", name, err)
+				cancel()
+			}
+		}()
+	}
+
+	for _, name := range names {
+		cache := caches[name]
+		result := func(readings []emhcasa.Reading, err error) {
+			cache.set(readings, err)
+			if api != nil {
+				api.Publish(name, readings, err)
+			} else {
+				health.Observe(name, err)
+			}
+			if err != nil {
+				fmt.Fprintf(env.stderr, "%s: %v\n", name, err)
+			}
+		}
+
+		p := &poll.Poller{
+			Gateway:  cache.client,
+			Interval: interval,
+			OnReadings: func(readings []emhcasa.Reading) {
+				result(readings, nil)
+				if st != nil {
+					if err := st.Add(historyReadings(name, len(names), readings)); err != nil {
+						fmt.Fprintf(env.stderr, "history: %v\n", err)
+					}
+				}
+			},
+			OnError: func(err error) { result(nil, err) },
+		}
+		start("poll "+name, p.Run)
+	}
+
+	if api != nil {
+		start("rest", func(ctx context.Context) error { return api.ListenAndServe(ctx, cfg.REST.Listen) })
+	}
+	if exporter != nil {
+		start("prometheus", func(ctx context.Context) error { return exporter.ListenAndServe(ctx, cfg.Prometheus.Listen) })
+	}
+
+	go daemon.Watchdog(ctx, health.Live)
+	_, _ = daemon.Notify(daemon.Ready)
+
+	wg.Wait()
+
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return nil
+	}
+}
+
+// historyReadings returns the readings to store. With several gateways, their
+// OBIS keys are prefixed by the gateway name like those of emhcasa.Aggregate
+// ("home/1.8.0"), so the counters of different meters aren't mixed.
+func historyReadings(name string, gateways int, readings []emhcasa.Reading) []emhcasa.Reading {
+	if gateways < 2 {
+		return readings
+	}
+
+	res := slices.Clone(readings)
+	for i := range res {
+		res[i].OBIS = name + "/" + res[i].OBIS
+	}
+	return res
+}
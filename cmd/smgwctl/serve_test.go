@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iseeberg79/emh-casa-go/store"
+)
+
+// freeAddr returns a free local TCP address
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+// TestServe tests polling and serving of the REST API and metrics
+func TestServe(t *testing.T) {
+	srv := gatewayServer(t)
+	restAddr, promAddr := freeAddr(t), freeAddr(t)
+
+	path := filepath.Join(t.TempDir(), "smgw.json")
+	cfg := fmt.Sprintf(`{
+		"interval": "50ms",
+		"gateways": {"home": {"uri": %q, "user": "admin", "password": "secret"}},
+		"rest": {"listen": %q, "tokens": ["token"]},
+		"prometheus": {"listen": %q}
+	}`, srv.URL, restAddr, promAddr)
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int, 1)
+	go func() {
+		code, _, _ := execute(ctx, "serve", "-config", path)
+		done <- code
+	}()
+
+	get := func(url, token string) (int, string) {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	var code int
+	for range 100 {
+		if code, _ = get("http://"+restAddr+"/readyz", ""); code == http.StatusOK {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if code != http.StatusOK {
+		t.Fatalf("readyz = %d", code)
+	}
+
+	if code, body := get("http://"+restAddr+"/api/v1/readings", "token"); code != http.StatusOK || !strings.Contains(body, `"obis":"16.7.0"`) {
+		t.Errorf("readings = %d %s", code, body)
+	}
+	if code, _ := get("http://"+restAddr+"/api/v1/readings", ""); code != http.StatusUnauthorized {
+		t.Errorf("readings without token = %d", code)
+	}
+	if code, body := get("http://"+promAddr+"/metrics", ""); code != http.StatusOK || !strings.Contains(body, `meter="1EMH0012345678"`) {
+		t.Errorf("metrics = %d %s", code, body)
+	}
+
+	cancel()
+	if code := <-done; code != 0 {
+		t.Errorf("serve exit code = %d", code)
+	}
+}
+
+// TestServeHistory tests that the history of several gateways is stored per gateway
+func TestServeHistory(t *testing.T) {
+	home, garage := gatewayServer(t), gatewayServer(t)
+	dir := t.TempDir()
+	history := filepath.Join(dir, "readings.jsonl")
+
+	path := filepath.Join(dir, "smgw.json")
+	cfg := fmt.Sprintf(`{
+		"interval": "50ms",
+		"gateways": {
+			"home": {"uri": %q, "user": "admin", "password": "secret"},
+			"garage": {"uri": %q, "user": "admin", "password": "secret"}
+		},
+		"history": {"path": %q}
+	}`, home.URL, garage.URL, history)
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int, 1)
+	go func() {
+		code, _, _ := execute(ctx, "serve", "-config", path)
+		done <- code
+	}()
+
+	// count returns the number of stored readings of code
+	count := func(code string) int {
+		st, err := store.OpenReadOnly(history)
+		if err != nil {
+			return 0
+		}
+		defer st.Close()
+
+		readings, _ := st.Query(code, time.Time{}, time.Now().Add(time.Hour))
+		return len(readings)
+	}
+
+	for range 100 {
+		if count("home/1.8.0") > 0 && count("garage/1.8.0") > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	if code := <-done; code != 0 {
+		t.Errorf("serve exit code = %d", code)
+	}
+
+	if count("home/1.8.0") == 0 || count("garage/1.8.0") == 0 {
+		t.Errorf("stored readings home = %d, garage = %d, want both", count("home/1.8.0"), count("garage/1.8.0"))
+	}
+	if n := count("1.8.0"); n != 0 {
+		t.Errorf("stored readings without gateway = %d, want 0", n)
+	}
+}
+
+// TestServeConfig tests configuration errors
+func TestServeConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, cfg := range map[string]string{
+		"unknown key":  `{"gateways": {"home": {}}, "mqtt": {}}`,
+		"no gateways":  `{"rest": {"listen": ":0"}}`,
+		"no exporters": `{"gateways": {"home": {"uri": "https://127.0.0.1", "user": "a", "password": "b"}}}`,
+		"duration":     `{"interval": 5}`,
+	} {
+		path := filepath.Join(dir, "smgw.json")
+		if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if code, _, errOut := execute(context.Background(), "serve", "-config", path); code != 1 || errOut == "" {
+			t.Errorf("%s: exit code = %d, stderr = %q", name, code, errOut)
+		}
+	}
+}
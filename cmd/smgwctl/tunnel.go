@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// tunnelCommands are the commands that can run through a tunnel
+var tunnelCommands = map[string]func(ctx context.Context, env *env, args []string) error{
+	"read":     read,
+	"meters":   meters,
+	"watch":    watch,
+	"diagnose": diagnose,
+}
+
+// tunnel runs a command through an SSH port forward to the gateway
+func tunnel(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("tunnel", env.stderr)
+	via := fs.String("via", os.Getenv("SMGW_VIA"), "SSH destination with access to the gateway, e.g. user@router ($SMGW_VIA)")
+	ssh := fs.String("ssh", "ssh", `SSH client command, e.g. "ssh -p 2222 -i key"`)
+	timeout := fs.Duration("timeout", 15*time.Second, "time to wait for the port forward")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	cmd := tunnelCommands[fs.Arg(0)]
+	if *via == "" || cmd == nil {
+		fmt.Fprintln(env.stderr, "usage: smgwctl tunnel -via user@host <read|meters|watch|diagnose> [flags]")
+		fs.Usage()
+		return errUsage
+	}
+
+	var stop func()
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+
+	inner := *env
+	inner.forward = func(gw *gatewayConfig) error {
+		var err error
+		stop, err = forwardSSH(ctx, *ssh, *via, gw, *timeout, env.stderr)
+		return err
+	}
+
+	return cmd(ctx, &inner, fs.Args()[1:])
+}
+
+// forwardSSH starts an SSH client forwarding a local port to the gateway
+// and points gw at it. Unless configured, the Host header keeps the gateway
+// address instead of the local port, as gateways may check it. The returned
+// function stops the client.
+func forwardSSH(ctx context.Context, command, via string, gw *gatewayConfig, timeout time.Duration, stderr io.Writer) (func(), error) {
+	if gw.URI == "" {
+		return nil, errors.New("tunnel requires the gateway address as seen from the SSH host (-uri)")
+	}
+
+	u, err := url.Parse(defaultScheme(gw.URI))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gateway URI: %w", err)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	local := l.Addr().String()
+	l.Close()
+
+	argv := strings.Fields(command)
+	if len(argv) == 0 {
+		return nil, errors.New("empty SSH command")
+	}
+	argv = append(argv, "-N", "-o", "ExitOnForwardFailure=yes", "-L", local+":"+net.JoinHostPort(u.Hostname(), port), via)
+
+	proc := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	proc.Stdout, proc.Stderr = stderr, stderr
+	if err := proc.Start(); err != nil {
+		return nil, fmt.Errorf("ssh: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- proc.Wait() }()
+
+	stop := func() {
+		_ = proc.Process.Kill()
+		<-exited
+	}
+
+	deadline := time.After(timeout)
+	for {
+		if conn, err := net.DialTimeout("tcp", local, time.Second); err == nil {
+			conn.Close()
+			break
+		}
+
+		select {
+		case err := <-exited:
+			return nil, fmt.Errorf("%w: ssh exited: %v", errUnreachable, err)
+		case <-deadline:
+			stop()
+			return nil, fmt.Errorf("%w: port forward via %s not ready after %v", errUnreachable, via, timeout)
+		case <-ctx.Done():
+			stop()
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if gw.HostHeader == "" {
+		gw.HostHeader = hostHeader(u)
+	}
+	gw.URI = u.Scheme + "://" + local
+
+	return stop, nil
+}
+
+// hostHeader returns the Host header of requests to u, without the zone of
+// IPv6 link-local addresses
+func hostHeader(u *url.URL) string {
+	host, _, _ := strings.Cut(u.Hostname(), "%")
+
+	switch {
+	case u.Port() != "":
+		return net.JoinHostPort(host, u.Port())
+	case strings.Contains(host, ":"):
+		return "[" + host + "]"
+	}
+	return host
+}
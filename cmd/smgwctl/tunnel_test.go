@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestMain runs the test binary as fake SSH client if requested by TestTunnel
+func TestMain(m *testing.M) {
+	if os.Getenv("SMGWCTL_FAKE_SSH") == "1" {
+		fakeSSH(os.Args[1:])
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeSSH serves the -L port forward of an SSH command line locally until killed
+func fakeSSH(args []string) {
+	var forward string
+	for i, arg := range args {
+		if arg == "-L" && i+1 < len(args) {
+			forward = args[i+1]
+		}
+	}
+
+	host, rest, _ := strings.Cut(forward, ":")
+	port, remote, _ := strings.Cut(rest, ":")
+
+	l, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		os.Exit(1)
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			os.Exit(1)
+		}
+
+		go func() {
+			defer conn.Close()
+
+			upstream, err := net.Dial("tcp", remote)
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+
+			go func() { _, _ = io.Copy(upstream, conn) }()
+			_, _ = io.Copy(conn, upstream)
+		}()
+	}
+}
+
+// TestTunnel tests commands through a port forward with the Host header of the gateway
+func TestTunnel(t *testing.T) {
+	srv := gatewayServer(t)
+
+	var (
+		mu    sync.Mutex
+		hosts = map[string]bool{}
+	)
+	handler := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts[r.Host] = true
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+
+	t.Setenv("SMGWCTL_FAKE_SSH", "1")
+
+	code, out, errOut := execute(context.Background(), "tunnel", "-via", "user@router", "-ssh", os.Args[0],
+		"read", "-uri", srv.URL, "-user", "admin", "-password", "secret", "-quiet", "-obis", "16.7.0")
+	if code != 0 || out != "500\n" {
+		t.Errorf("tunnel read = %d %q %q", code, out, errOut)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := strings.TrimPrefix(srv.URL, "https://"); len(hosts) != 1 || !hosts[want] {
+		t.Errorf("Host = %v, want %s", hosts, want)
+	}
+
+	if code, _, _ := execute(context.Background(), "tunnel", "-via", "user@router", "serve"); code != 64 {
+		t.Errorf("tunnel serve = %d, want 64", code)
+	}
+	if code, _, _ := execute(context.Background(), "tunnel", "-via", "user@router", "-ssh", "/nonexistent", "read", "-uri", srv.URL); code != 1 {
+		t.Errorf("tunnel without ssh = %d, want 1", code)
+	}
+}
@@ -0,0 +1,67 @@
+package emhcasa
+
+import (
+	"fmt"
+	"time"
+)
+
+// ConsistencyChecker cross-checks instantaneous power against the energy registers.
+// It integrates the net grid power over time and compares the result with the
+// change of the net energy registers (1.8.0 - 2.8.0). A large drift indicates
+// scaling or unit errors in the reported values.
+//
+// The zero value is ready to use. Feed it with regularly polled values;
+// the shorter the interval, the more accurate the integration.
+type ConsistencyChecker struct {
+	samples    int
+	last       time.Time
+	lastPowerW float64
+	integrated float64 // kWh
+	firstkWh   float64
+	lastkWh    float64
+}
+
+// Add records meter values returned by GetMeterValues at time t.
+func (cc *ConsistencyChecker) Add(t time.Time, values map[string]float64) {
+	s := NewGridSnapshot(values)
+	power := s.ImportW - s.ExportW
+	energy := s.ImportkWh - s.ExportkWh
+
+	if cc.samples == 0 {
+		cc.firstkWh = energy
+	} else {
+		// trapezoidal integration, W*h → kWh
+		hours := t.Sub(cc.last).Hours()
+		cc.integrated += (cc.lastPowerW + power) / 2 * hours / 1000
+	}
+
+	cc.samples++
+	cc.last = t
+	cc.lastPowerW = power
+	cc.lastkWh = energy
+}
+
+// IntegratedkWh returns the net energy obtained by integrating power samples.
+func (cc *ConsistencyChecker) IntegratedkWh() float64 {
+	return cc.integrated
+}
+
+// RegisterkWh returns the net energy change reported by the energy registers.
+func (cc *ConsistencyChecker) RegisterkWh() float64 {
+	return cc.lastkWh - cc.firstkWh
+}
+
+// Drift returns the deviation of the integrated energy from the register change in percent.
+// Returns an error if fewer than two samples were added or the registers did not change.
+func (cc *ConsistencyChecker) Drift() (float64, error) {
+	if cc.samples < 2 {
+		return 0, fmt.Errorf("not enough samples: %d", cc.samples)
+	}
+
+	delta := cc.RegisterkWh()
+	if delta == 0 {
+		return 0, fmt.Errorf("energy registers did not change")
+	}
+
+	return (cc.integrated - delta) / delta * 100, nil
+}
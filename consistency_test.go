@@ -0,0 +1,77 @@
+package emhcasa
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestConsistencyChecker tests drift between integrated power and energy registers
+func TestConsistencyChecker(t *testing.T) {
+	tests := []struct {
+		name      string
+		power     float64 // constant import power in W
+		kWhPerMin float64 // register increase per minute
+		wantDrift float64
+	}{
+		{
+			name:      "consistent values",
+			power:     6000,
+			kWhPerMin: 0.1,
+			wantDrift: 0,
+		},
+		{
+			name:      "power scaled by factor 10",
+			power:     60000,
+			kWhPerMin: 0.1,
+			wantDrift: 900,
+		},
+		{
+			name:      "export",
+			power:     -3000,
+			kWhPerMin: -0.05,
+			wantDrift: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cc ConsistencyChecker
+			start := time.Now()
+
+			for i := range 11 {
+				energy := float64(i) * tt.kWhPerMin
+				values := map[string]float64{"16.7.0": tt.power, "1.8.0": 100, "2.8.0": 50}
+				if energy >= 0 {
+					values["1.8.0"] += energy
+				} else {
+					values["2.8.0"] -= energy
+				}
+				cc.Add(start.Add(time.Duration(i)*time.Minute), values)
+			}
+
+			drift, err := cc.Drift()
+			if err != nil {
+				t.Fatalf("Drift() error = %v", err)
+			}
+			if math.Abs(drift-tt.wantDrift) > 0.001 {
+				t.Errorf("Drift() = %v, want %v", drift, tt.wantDrift)
+			}
+		})
+	}
+}
+
+// TestConsistencyCheckerNotEnoughData tests drift errors without usable data
+func TestConsistencyCheckerNotEnoughData(t *testing.T) {
+	var cc ConsistencyChecker
+	if _, err := cc.Drift(); err == nil {
+		t.Error("Drift() expected error without samples")
+	}
+
+	now := time.Now()
+	cc.Add(now, map[string]float64{"16.7.0": 0, "1.8.0": 100})
+	cc.Add(now.Add(time.Minute), map[string]float64{"16.7.0": 0, "1.8.0": 100})
+	if _, err := cc.Drift(); err == nil {
+		t.Error("Drift() expected error for unchanged registers")
+	}
+}
@@ -0,0 +1,107 @@
+// Package daemon supports running a poller or exporter as a supervised
+// service: the systemd notify protocol (sd_notify) for readiness and
+// watchdog, and graceful shutdown on SIGTERM.
+//
+// Use Type=notify and WatchdogSec= in the unit file. Outside systemd the
+// notify functions do nothing.
+package daemon
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Notification states, see sd_notify(3)
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Alive    = "WATCHDOG=1"
+)
+
+// Notify sends a state to the service manager. It returns false without
+// error if the process is not started by systemd with notification support.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// abstract namespace sockets are passed with a leading @
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog timeout configured by WatchdogSec=,
+// or 0 if the watchdog is disabled for this process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Watchdog keeps the systemd watchdog alive until ctx is cancelled,
+// notifying at half the watchdog interval as long as healthy returns true,
+// e.g. while the last successful poll is recent. If polling wedges, the
+// notifications stop and systemd restarts the service. Watchdog returns
+// immediately if the watchdog is disabled.
+func Watchdog(ctx context.Context, healthy func() bool) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		if healthy() {
+			_, _ = Notify(Alive)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SignalContext returns a copy of ctx that is cancelled on SIGTERM or
+// SIGINT, so servers and pollers shut down gracefully when the service is
+// stopped. A second signal terminates the process immediately.
+func SignalContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+
+	go func() {
+		<-ctx.Done()
+		_, _ = Notify(Stopping)
+		cancel() // restores default signal handling
+	}()
+
+	return ctx, cancel
+}
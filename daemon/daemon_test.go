@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listen creates a notification socket and points NOTIFY_SOCKET to it
+func listen(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram not supported: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	return conn
+}
+
+// receive reads the next notification
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	buf := make([]byte, 64)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	return string(buf[:n])
+}
+
+// TestNotify tests notifications with and without service manager
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if ok, err := Notify(Ready); ok || err != nil {
+		t.Errorf("Notify() without socket = %v, %v", ok, err)
+	}
+
+	conn := listen(t)
+	if ok, err := Notify(Ready); !ok || err != nil {
+		t.Fatalf("Notify() = %v, %v", ok, err)
+	}
+	if got := receive(t, conn); got != Ready {
+		t.Errorf("received %q, want %q", got, Ready)
+	}
+}
+
+// TestWatchdog tests the watchdog configuration and notifications
+func TestWatchdog(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	if d := WatchdogInterval(); d != 0 {
+		t.Errorf("WatchdogInterval() without watchdog = %v", d)
+	}
+
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "1")
+	if d := WatchdogInterval(); d != 0 && os.Getpid() != 1 {
+		t.Errorf("WatchdogInterval() for other process = %v", d)
+	}
+
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if d := WatchdogInterval(); d != 20*time.Millisecond {
+		t.Errorf("WatchdogInterval() = %v, want 20ms", d)
+	}
+
+	conn := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Watchdog(ctx, func() bool { return true })
+		close(done)
+	}()
+
+	for range 2 {
+		if got := receive(t, conn); got != Alive {
+			t.Errorf("received %q, want %q", got, Alive)
+		}
+	}
+
+	cancel()
+	<-done
+}
@@ -1,13 +1,49 @@
 package emhcasa
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/pion/mdns/v2"
 	"github.com/tobima/smgw-discover-go/smgw"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
+// DiscoveryConfig configures mDNS gateway discovery.
+// Zero values select the defaults used by DiscoverGatewayURI.
+type DiscoveryConfig struct {
+	Hostname   string          // mDNS name to query (default "smgw.local")
+	Timeout    time.Duration   // query timeout per address family (default 300ms)
+	Interfaces []net.Interface // interfaces to query on (default all)
+	Network    string          // preferred address family "ip4" or "ip6", queried before the other one (default "" for both at once)
+}
+
+// DiscoveryEventType describes what changed between two discovery runs.
+type DiscoveryEventType int
+
+const (
+	GatewayAppeared    DiscoveryEventType = iota // gateway found after being absent
+	GatewayDisappeared                           // gateway no longer answers mDNS queries
+	GatewayChanged                               // gateway answers with a different address
+)
+
+// DiscoveryEvent is emitted by WatchGateway when the discovered gateway changes.
+type DiscoveryEvent struct {
+	Type        DiscoveryEventType
+	URI         string // current gateway URI, empty if disappeared
+	PreviousURI string // last known gateway URI, empty if appeared
+}
+
 // DiscoverGatewayURI discovers the CASA gateway via mDNS by querying for "smgw.local".
-// Returns a fully-formed URI (e.g., "https://[fe80::dead:beef%eth0]") ready for use.
+// Returns a fully-formed URI (e.g., "https://[fe80::dead:beef%25eth0]") ready for use,
+// with IPv6 zone identifiers escaped as required by RFC 6874 and net/url.
 // Uses the smgw-discover-go module which implements a 300ms timeout.
 // Returns an error if no gateway is found.
 func DiscoverGatewayURI() (string, error) {
@@ -17,9 +53,173 @@ func DiscoverGatewayURI() (string, error) {
 		return "", fmt.Errorf("failed to discover gateway: %w", err)
 	}
 
-	// The smgw.Discover() already returns the host in the correct format:
-	// - IPv6: [fe80::dead:beef:cafe:babe%eth1]
-	// - IPv4: 192.168.1.100
-	// Just prepend the HTTPS scheme
-	return fmt.Sprintf("https://%s", host), nil
+	return hostURI(host), nil
+}
+
+// hostURI formats a host returned by smgw.Discover, e.g. "192.168.1.100" or
+// "[fe80::dead:beef:cafe:babe%eth1]", like gatewayURI
+func hostURI(host string) string {
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+	if err != nil {
+		return "https://" + host
+	}
+	return gatewayURI(addr)
+}
+
+// DiscoverGatewayURIWithConfig discovers the CASA gateway via mDNS using the given configuration.
+// Use this instead of DiscoverGatewayURI on busy networks where 300ms is too short,
+// or to restrict discovery to specific interfaces or prefer an address family.
+// With a preferred Network the other family is only queried if the gateway
+// does not answer on the preferred one.
+// Returns an error if no gateway is found within the timeout.
+func DiscoverGatewayURIWithConfig(cfg DiscoveryConfig) (string, error) {
+	return discoverWithConfig(cfg, queryAddr)
+}
+
+// discoverWithConfig applies the defaults of cfg and queries the address
+// families in order of preference
+func discoverWithConfig(cfg DiscoveryConfig, query func(cfg DiscoveryConfig, network string) (netip.Addr, error)) (string, error) {
+	var networks []string
+	switch cfg.Network {
+	case "":
+		networks = []string{""}
+	case "ip4":
+		networks = []string{"ip4", "ip6"}
+	case "ip6":
+		networks = []string{"ip6", "ip4"}
+	default:
+		return "", fmt.Errorf("invalid discovery network: %q", cfg.Network)
+	}
+
+	if cfg.Hostname == "" {
+		cfg.Hostname = "smgw.local"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 300 * time.Millisecond
+	}
+
+	var errs []error
+	for _, network := range networks {
+		addr, err := query(cfg, network)
+		if err == nil {
+			return gatewayURI(addr), nil
+		}
+		errs = append(errs, err)
+	}
+
+	return "", errors.Join(errs...)
+}
+
+// queryAddr queries the address of cfg.Hostname via mDNS on the given
+// network, "ip4", "ip6" or "" for both
+func queryAddr(cfg DiscoveryConfig, network string) (netip.Addr, error) {
+	var conn4 *ipv4.PacketConn
+	if network != "ip6" {
+		addr, err := net.ResolveUDPAddr("udp4", mdns.DefaultAddressIPv4)
+		if err != nil {
+			return netip.Addr{}, err
+		}
+		l, err := net.ListenUDP("udp4", addr)
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("failed to open IPv4 mDNS socket: %w", err)
+		}
+		conn4 = ipv4.NewPacketConn(l)
+	}
+
+	var conn6 *ipv6.PacketConn
+	if network != "ip4" {
+		addr, err := net.ResolveUDPAddr("udp6", mdns.DefaultAddressIPv6)
+		if err != nil {
+			return netip.Addr{}, err
+		}
+		l, err := net.ListenUDP("udp6", addr)
+		if err != nil {
+			if conn4 != nil {
+				conn4.Close()
+			}
+			return netip.Addr{}, fmt.Errorf("failed to open IPv6 mDNS socket: %w", err)
+		}
+		conn6 = ipv6.NewPacketConn(l)
+	}
+
+	server, err := mdns.Server(conn4, conn6, &mdns.Config{
+		Interfaces: cfg.Interfaces,
+	})
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to start mDNS: %w", err)
+	}
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	_, addr, err := server.QueryAddr(ctx, cfg.Hostname)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to discover gateway: %w", err)
+	}
+
+	return addr, nil
+}
+
+// gatewayURI formats a discovered address as HTTPS URI, escaping IPv6 zone identifiers
+func gatewayURI(addr netip.Addr) string {
+	if addr.Is6() && !addr.Is4In6() {
+		return fmt.Sprintf("https://[%s]", url.PathEscape(addr.String()))
+	}
+	return fmt.Sprintf("https://%s", addr.Unmap())
+}
+
+// WatchGateway repeats gateway discovery at the given interval and reports when
+// the gateway appears, disappears or changes its address (e.g. after DHCP renewal
+// or a firmware update). The channel is closed when ctx is cancelled.
+func WatchGateway(ctx context.Context, interval time.Duration) (<-chan DiscoveryEvent, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid watch interval: %v", interval)
+	}
+
+	events := make(chan DiscoveryEvent)
+	go watchGateway(ctx, interval, DiscoverGatewayURI, events)
+
+	return events, nil
+}
+
+// watchGateway runs the discovery loop and emits events on address changes
+func watchGateway(ctx context.Context, interval time.Duration, discover func() (string, error), events chan<- DiscoveryEvent) {
+	defer close(events)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var current string
+	for {
+		uri, err := discover()
+		if err != nil {
+			uri = ""
+		}
+
+		if uri != current {
+			ev := DiscoveryEvent{URI: uri, PreviousURI: current}
+			switch {
+			case current == "":
+				ev.Type = GatewayAppeared
+			case uri == "":
+				ev.Type = GatewayDisappeared
+			default:
+				ev.Type = GatewayChanged
+			}
+
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+			current = uri
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
 }
@@ -0,0 +1,185 @@
+package emhcasa
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWatchGateway tests event generation from consecutive discovery results
+func TestWatchGateway(t *testing.T) {
+	results := []string{"", "https://192.168.33.2", "https://192.168.33.2", "https://192.168.33.5", ""}
+	want := []DiscoveryEvent{
+		{Type: GatewayAppeared, URI: "https://192.168.33.2"},
+		{Type: GatewayChanged, URI: "https://192.168.33.5", PreviousURI: "https://192.168.33.2"},
+		{Type: GatewayDisappeared, PreviousURI: "https://192.168.33.5"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var call int
+	discover := func() (string, error) {
+		uri := results[min(call, len(results)-1)]
+		call++
+		if uri == "" {
+			return "", errors.New("not found")
+		}
+		return uri, nil
+	}
+
+	events := make(chan DiscoveryEvent)
+	go watchGateway(ctx, time.Millisecond, discover, events)
+
+	for i, w := range want {
+		select {
+		case got := <-events:
+			if got != w {
+				t.Errorf("event %d = %+v, want %+v", i, got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for event %d", i)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+// TestWatchGatewayInvalidInterval tests interval validation
+func TestWatchGatewayInvalidInterval(t *testing.T) {
+	if _, err := WatchGateway(context.Background(), 0); err == nil {
+		t.Error("WatchGateway() expected error for zero interval")
+	}
+}
+
+// TestGatewayURI tests URI formatting of discovered addresses
+func TestGatewayURI(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		want string
+	}{
+		{
+			name: "IPv4",
+			addr: "192.168.33.2",
+			want: "https://192.168.33.2",
+		},
+		{
+			name: "IPv4-mapped IPv6",
+			addr: "::ffff:192.168.33.2",
+			want: "https://192.168.33.2",
+		},
+		{
+			name: "IPv6 link-local with zone",
+			addr: "fe80::dead:beef%eth0",
+			want: "https://[fe80::dead:beef%25eth0]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gatewayURI(netip.MustParseAddr(tt.addr))
+			if got != tt.want {
+				t.Errorf("gatewayURI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHostURI tests URI formatting of hosts returned by smgw.Discover
+func TestHostURI(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "192.168.33.2", want: "https://192.168.33.2"},
+		{host: "[fe80::dead:beef%eth0]", want: "https://[fe80::dead:beef%25eth0]"},
+		{host: "smgw.local", want: "https://smgw.local"},
+	}
+
+	for _, tt := range tests {
+		if got := hostURI(tt.host); got != tt.want {
+			t.Errorf("hostURI(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+// TestDiscoveryNetwork tests validation of the address family
+func TestDiscoveryNetwork(t *testing.T) {
+	if _, err := DiscoverGatewayURIWithConfig(DiscoveryConfig{Network: "tcp"}); err == nil || !strings.Contains(err.Error(), "invalid discovery network") {
+		t.Errorf("DiscoverGatewayURIWithConfig() error = %v, want invalid network", err)
+	}
+}
+
+// TestDiscoverWithConfig tests defaults and the order of the queried address families
+func TestDiscoverWithConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		answers map[string]string // address per answering network
+		want    string
+		queried []string
+	}{
+		{
+			name:    "both",
+			answers: map[string]string{"": "192.168.33.2"},
+			want:    "https://192.168.33.2",
+			queried: []string{""},
+		},
+		{
+			name:    "preferred IPv6",
+			network: "ip6",
+			answers: map[string]string{"ip4": "192.168.33.2", "ip6": "fe80::dead:beef%eth0"},
+			want:    "https://[fe80::dead:beef%25eth0]",
+			queried: []string{"ip6"},
+		},
+		{
+			name:    "fallback to IPv4",
+			network: "ip6",
+			answers: map[string]string{"ip4": "192.168.33.2"},
+			want:    "https://192.168.33.2",
+			queried: []string{"ip6", "ip4"},
+		},
+		{
+			name:    "fallback to IPv6",
+			network: "ip4",
+			answers: map[string]string{"ip6": "fe80::dead:beef%eth0"},
+			want:    "https://[fe80::dead:beef%25eth0]",
+			queried: []string{"ip4", "ip6"},
+		},
+		{
+			name:    "not found",
+			network: "ip4",
+			queried: []string{"ip4", "ip6"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queried []string
+			query := func(cfg DiscoveryConfig, network string) (netip.Addr, error) {
+				if cfg.Hostname != "smgw.local" || cfg.Timeout != 300*time.Millisecond {
+					t.Errorf("query config = %+v, want defaults", cfg)
+				}
+				queried = append(queried, network)
+				if addr, ok := tt.answers[network]; ok {
+					return netip.MustParseAddr(addr), nil
+				}
+				return netip.Addr{}, errors.New("timeout")
+			}
+
+			got, err := discoverWithConfig(DiscoveryConfig{Network: tt.network}, query)
+			if got != tt.want || (err != nil) != (tt.want == "") {
+				t.Errorf("discoverWithConfig() = %v, %v, want %v", got, err, tt.want)
+			}
+			if strings.Join(queried, ",") != strings.Join(tt.queried, ",") {
+				t.Errorf("queried networks = %q, want %q", queried, tt.queried)
+			}
+		})
+	}
+}
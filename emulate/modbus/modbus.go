@@ -0,0 +1,180 @@
+// Package modbus serves readings as a SunSpec meter over Modbus TCP.
+//
+// Inverters, wallboxes and energy management systems that support SunSpec
+// meters can read the grid connection point from the smart meter gateway.
+// The register map starts at BaseAddress with the common model (1) followed
+// by the three phase meter model (203, integer with scale factors). Active
+// power is signed, positive = import. Points without reading are reported
+// as not implemented.
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// Modbus function and exception codes
+const (
+	fcReadHoldingRegisters = 0x03
+	fcReadInputRegisters   = 0x04
+
+	exIllegalFunction    = 0x01
+	exIllegalDataAddress = 0x02
+	exIllegalDataValue   = 0x03
+)
+
+// maxRegisters is the maximum number of registers per read request
+const maxRegisters = 125
+
+// Server is a Modbus TCP server answering register reads from the SunSpec
+// map of the latest readings. It responds to any unit ID.
+type Server struct {
+	serial string
+
+	mu   sync.RWMutex
+	regs []uint16
+}
+
+// New creates a Server reporting serial (e.g. the meter ID) in the common
+// model. Until the first Update all meter points are not implemented.
+func New(serial string) *Server {
+	return &Server{serial: serial, regs: registerMap(serial, nil)}
+}
+
+// Update replaces the served readings, e.g. from poll.Poller callbacks.
+// Readings of invalid quality are ignored.
+func (s *Server) Update(readings []emhcasa.Reading) {
+	regs := registerMap(s.serial, readings)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.regs = regs
+}
+
+// ListenAndServe listens on the TCP address addr (e.g. ":502") and serves
+// until ctx is cancelled. It returns ctx.Err().
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve accepts connections on l until ctx is cancelled, then closes l and
+// all connections and returns ctx.Err().
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// serveConn answers requests on conn until the client disconnects, a
+// malformed frame is received, or ctx is cancelled
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	for {
+		// MBAP header: transaction ID, protocol ID, length, unit ID
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+
+		length := binary.BigEndian.Uint16(header[4:])
+		if binary.BigEndian.Uint16(header[2:]) != 0 || length < 2 || length > 254 {
+			return
+		}
+
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		resp := s.handle(pdu)
+
+		frame := make([]byte, 7, 7+len(resp))
+		copy(frame, header)
+		binary.BigEndian.PutUint16(frame[4:], uint16(len(resp)+1))
+
+		if _, err := conn.Write(append(frame, resp...)); err != nil {
+			return
+		}
+	}
+}
+
+// handle returns the response PDU for a request PDU
+func (s *Server) handle(pdu []byte) []byte {
+	fc := pdu[0]
+	if fc != fcReadHoldingRegisters && fc != fcReadInputRegisters {
+		return []byte{fc | 0x80, exIllegalFunction}
+	}
+	if len(pdu) != 5 {
+		return []byte{fc | 0x80, exIllegalDataValue}
+	}
+
+	addr := int(binary.BigEndian.Uint16(pdu[1:]))
+	count := int(binary.BigEndian.Uint16(pdu[3:]))
+	if count < 1 || count > maxRegisters {
+		return []byte{fc | 0x80, exIllegalDataValue}
+	}
+
+	regs, err := s.read(addr, count)
+	if err != nil {
+		return []byte{fc | 0x80, exIllegalDataAddress}
+	}
+
+	resp := []byte{fc, byte(2 * count)}
+	for _, r := range regs {
+		resp = binary.BigEndian.AppendUint16(resp, r)
+	}
+	return resp
+}
+
+// errAddress is returned for reads outside the register map
+var errAddress = errors.New("illegal data address")
+
+// read returns count registers starting at addr
+func (s *Server) read(addr, count int) ([]uint16, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := addr - BaseAddress
+	if start < 0 || start+count > len(s.regs) {
+		return nil, errAddress
+	}
+
+	return append([]uint16(nil), s.regs[start:start+count]...), nil
+}
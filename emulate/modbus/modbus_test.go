@@ -0,0 +1,144 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// meterBase is the address of the first point of the meter model
+const meterBase = BaseAddress + 2 + 2 + modelCommonLength + 2
+
+// TestRegisterMap tests the SunSpec model layout and point encoding
+func TestRegisterMap(t *testing.T) {
+	s := New("1EMH0012345678")
+	s.Update([]emhcasa.Reading{
+		{OBIS: "16.7.0", Value: -1500},
+		{OBIS: "36.7.0", Value: 40000},
+		{OBIS: "31.7.0", Value: 6.5},
+		{OBIS: "32.7.0", Value: 230.1},
+		{OBIS: "52.7.0", Value: 229.9},
+		{OBIS: "1.8.0", Value: 12345.678},
+		{OBIS: "2.8.0", Value: 1, Quality: emhcasa.QualityInvalid},
+	})
+
+	regs, err := s.read(BaseAddress, len(s.regs))
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+
+	at := func(addr int) uint16 { return regs[addr-BaseAddress] }
+	signed := func(addr int) int16 { return int16(at(addr)) }
+
+	if at(BaseAddress) != 0x5375 || at(BaseAddress+1) != 0x6e53 || at(BaseAddress+2) != modelCommon {
+		t.Errorf("header = %04x %04x %d", at(BaseAddress), at(BaseAddress+1), at(BaseAddress+2))
+	}
+	if at(meterBase-2) != modelMeter || at(meterBase-1) != modelMeterLength {
+		t.Errorf("meter model header = %d %d", at(meterBase-2), at(meterBase-1))
+	}
+	if end := meterBase + modelMeterLength; at(end) != modelEnd || at(end+1) != 0 || len(regs) != end+2-BaseAddress {
+		t.Errorf("end model = %04x %d, map length %d", at(end), at(end+1), len(regs))
+	}
+
+	tests := []struct {
+		name string
+		off  int
+		want int16
+	}{
+		{"A", offA, 650},
+		{"AphA", offA + 1, 650},
+		{"AphB", offA + 2, notImplInt16 - 0x10000},
+		{"A_SF", offASF, -2},
+		{"PhV", offPhV, 2300},
+		{"V_SF", offVSF, -1},
+		{"Hz_SF", offHzSF, notImplInt16 - 0x10000},
+		{"W", offW, -150},
+		{"WphA", offW + 1, 4000},
+		{"W_SF", offWSF, 1},
+		{"TotWh_SF", offTotWhSF, 0},
+	}
+
+	for _, tt := range tests {
+		if got := signed(meterBase + tt.off); got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if imp := uint32(at(meterBase+offTotWhImp))<<16 | uint32(at(meterBase+offTotWhImp+1)); imp != 12345678 {
+		t.Errorf("TotWhImp = %d, want 12345678", imp)
+	}
+	if exp := at(meterBase+offTotWhExp) | at(meterBase+offTotWhExp+1); exp != 0 {
+		t.Errorf("TotWhExp = %d, want 0 for invalid reading", exp)
+	}
+
+	if _, err := s.read(BaseAddress-1, 2); !errors.Is(err, errAddress) {
+		t.Errorf("read() before map error = %v", err)
+	}
+}
+
+// TestServe tests Modbus TCP requests and exceptions
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	request := func(fc byte, addr, count uint16) []byte {
+		req := []byte{0x12, 0x34, 0, 0, 0, 6, 1, fc}
+		req = binary.BigEndian.AppendUint16(req, addr)
+		req = binary.BigEndian.AppendUint16(req, count)
+		if _, err := conn.Write(req); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			t.Fatalf("ReadFull() error = %v", err)
+		}
+		if !bytes.Equal(header[:4], []byte{0x12, 0x34, 0, 0}) || header[6] != 1 {
+			t.Errorf("response header = %x", header)
+		}
+
+		pdu := make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			t.Fatalf("ReadFull() error = %v", err)
+		}
+		return pdu
+	}
+
+	if got, want := request(fcReadHoldingRegisters, BaseAddress, 2), []byte{3, 4, 0x53, 0x75, 0x6e, 0x53}; !bytes.Equal(got, want) {
+		t.Errorf("read SunS = %x, want %x", got, want)
+	}
+	if got, want := request(fcReadInputRegisters, 0, 1), []byte{0x84, exIllegalDataAddress}; !bytes.Equal(got, want) {
+		t.Errorf("read address 0 = %x, want %x", got, want)
+	}
+	if got, want := request(fcReadHoldingRegisters, BaseAddress, 200), []byte{0x83, exIllegalDataValue}; !bytes.Equal(got, want) {
+		t.Errorf("read 200 registers = %x, want %x", got, want)
+	}
+	if got, want := request(0x06, BaseAddress, 1), []byte{0x86, exIllegalFunction}; !bytes.Equal(got, want) {
+		t.Errorf("write register = %x, want %x", got, want)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Serve() error = %v, want %v", err, context.Canceled)
+	}
+}
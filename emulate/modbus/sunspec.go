@@ -0,0 +1,273 @@
+package modbus
+
+import (
+	"math"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// BaseAddress is the register address of the SunSpec map ("SunS" marker).
+const BaseAddress = 40000
+
+// SunSpec model IDs and lengths served
+const (
+	modelCommon       = 1
+	modelCommonLength = 65
+	modelMeter        = 203 // three phase wye-connect meter, integer with scale factors
+	modelMeterLength  = 105
+	modelEnd          = 0xFFFF
+)
+
+// notImplInt16 marks unimplemented int16 points and scale factors
+const notImplInt16 = 0x8000
+
+// offsets of points within the meter model, relative to the first register after ID and length
+const (
+	offA        = 0 // AphA-C at 1-3
+	offASF      = 4
+	offPhV      = 5  // PhVphA-C at 6-8
+	offVSF      = 13 // line-to-line voltages at 9-12 are not implemented
+	offHz       = 14
+	offHzSF     = 15
+	offW        = 16 // WphA-C at 17-19
+	offWSF      = 20
+	offVA       = 21 // VAphA-C at 22-24
+	offVASF     = 25
+	offVAR      = 26 // VARphA-C at 27-29
+	offVARSF    = 30
+	offPF       = 31 // PFphA-C at 32-34
+	offPFSF     = 35
+	offTotWhExp = 36 // acc32, per phase at 38-43
+	offTotWhImp = 44 // acc32, per phase at 46-51
+	offTotWhSF  = 52
+	offTotVAhSF = 69  // apparent energies at 53-68 are not implemented
+	offTotVArSF = 102 // reactive energies at 70-101 are not implemented
+)
+
+// registerMap builds the SunSpec register map for the given serial number
+// and readings. Points without reading are marked as not implemented.
+func registerMap(serial string, readings []emhcasa.Reading) []uint16 {
+	regs := []uint16{0x5375, 0x6e53} // "SunS"
+
+	regs = append(regs, modelCommon, modelCommonLength)
+	regs = appendString(regs, "EMH", 16)        // Mn
+	regs = appendString(regs, "CASA", 16)       // Md
+	regs = appendString(regs, "", 8)            // Opt
+	regs = appendString(regs, "emh-casa-go", 8) // Vr
+	regs = appendString(regs, serial, 16)       // SN
+	regs = append(regs, 1)                      // DA
+
+	regs = append(regs, modelMeter, modelMeterLength)
+	regs = append(regs, meterModel(emhcasa.Values(valid(readings)))...)
+
+	return append(regs, modelEnd, 0)
+}
+
+// valid returns the readings not marked invalid
+func valid(readings []emhcasa.Reading) []emhcasa.Reading {
+	var res []emhcasa.Reading
+	for _, r := range readings {
+		if r.Quality != emhcasa.QualityInvalid {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// meterModel encodes the points of model 203
+func meterModel(values map[string]float64) []uint16 {
+	m := make([]uint16, modelMeterLength)
+	for _, sf := range []int{offASF, offVSF, offHzSF, offWSF, offVASF, offVARSF, offPFSF, offTotWhSF, offTotVAhSF, offTotVArSF} {
+		m[sf] = notImplInt16
+	}
+	for i := offPhV + 4; i < offVSF; i++ {
+		m[i] = notImplInt16 // line-to-line voltages
+	}
+
+	phases := func(l1, l2, l3 string) [3]*float64 {
+		var res [3]*float64
+		for i, code := range []string{l1, l2, l3} {
+			if v, ok := values[code]; ok {
+				res[i] = &v
+			}
+		}
+		return res
+	}
+
+	// current: total is the sum of the phases
+	current := phases(obis.CurrentL1, obis.CurrentL2, obis.CurrentL3)
+	block(m, offA, offASF, -2, sum(current), current)
+
+	// voltage: total is the average line-to-neutral voltage
+	voltage := phases(obis.VoltageL1, obis.VoltageL2, obis.VoltageL3)
+	block(m, offPhV, offVSF, -1, avg(voltage), voltage)
+
+	freq, ok := lookup(values, obis.Frequency)
+	block(m, offHz, offHzSF, -2, ptr(freq, ok), [3]*float64{})
+
+	// active power: signed, positive = import
+	var power *float64
+	if hasKey(values, obis.PowerImport, obis.PowerExport, obis.Power) {
+		p, _ := emhcasa.PowerConfig{}.Normalize(values)
+		power = &p
+	}
+	phaseCodes := [3][]string{
+		{obis.PowerL1, obis.PowerImportL1, obis.PowerExportL1},
+		{obis.PowerL2, obis.PowerImportL2, obis.PowerExportL2},
+		{obis.PowerL3, obis.PowerImportL3, obis.PowerExportL3},
+	}
+	var phasePower [3]*float64
+	for i, p := range emhcasa.Phases(values) {
+		if hasKey(values, phaseCodes[i]...) {
+			phasePower[i] = &p.PowerW
+		}
+	}
+	block(m, offW, offWSF, 0, power, phasePower)
+
+	apparent, ok := lookup(values, obis.ApparentPower)
+	block(m, offVA, offVASF, 0, ptr(apparent, ok), phases(obis.ApparentPowerL1, obis.ApparentPowerL2, obis.ApparentPowerL3))
+
+	// reactive power: Q+ minus Q-
+	var reactive *float64
+	if hasKey(values, obis.ReactivePowerImport, obis.ReactivePowerExport) {
+		q := values[obis.ReactivePowerImport] - values[obis.ReactivePowerExport]
+		reactive = &q
+	}
+	block(m, offVAR, offVARSF, 0, reactive, [3]*float64{})
+
+	// power factor in percent
+	pf, ok := lookup(values, obis.PowerFactor)
+	pfPhases := phases(obis.PowerFactorL1, obis.PowerFactorL2, obis.PowerFactorL3)
+	for _, p := range pfPhases {
+		if p != nil {
+			*p *= 100
+		}
+	}
+	block(m, offPF, offPFSF, -2, ptr(pf*100, ok), pfPhases)
+
+	// energy counters in Wh
+	exp, hasExp := lookup(values, obis.EnergyExport)
+	imp, hasImp := lookup(values, obis.EnergyImport)
+	if hasExp || hasImp {
+		sf := accScale(exp*1000, imp*1000)
+		m[offTotWhSF] = uint16(int16(sf))
+		if hasExp {
+			putAcc32(m, offTotWhExp, exp*1000, sf)
+		}
+		if hasImp {
+			putAcc32(m, offTotWhImp, imp*1000, sf)
+		}
+	}
+
+	return m
+}
+
+// block encodes a total with three phase values and their shared scale
+// factor, using the smallest scale factor from minSF on that fits int16
+func block(m []uint16, off, sfOff, minSF int, total *float64, phases [3]*float64) {
+	values := append([]*float64{total}, phases[:]...)
+
+	sf, found := minSF, false
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		found = true
+		for math.Abs(math.Round(*v/math.Pow10(sf))) > math.MaxInt16 {
+			sf++
+		}
+	}
+
+	for i, v := range values {
+		m[off+i] = notImplInt16
+		if v != nil {
+			m[off+i] = uint16(int16(math.Round(*v / math.Pow10(sf))))
+		}
+	}
+
+	if found {
+		m[sfOff] = uint16(int16(sf))
+	}
+}
+
+// accScale returns the smallest non-negative scale factor fitting the values into uint32
+func accScale(values ...float64) int {
+	sf := 0
+	for _, v := range values {
+		for math.Round(math.Abs(v)/math.Pow10(sf)) > math.MaxUint32 {
+			sf++
+		}
+	}
+	return sf
+}
+
+// putAcc32 writes a scaled acc32 value as two registers, high word first
+func putAcc32(m []uint16, off int, v float64, sf int) {
+	n := uint32(math.Round(math.Abs(v) / math.Pow10(sf)))
+	m[off], m[off+1] = uint16(n>>16), uint16(n)
+}
+
+// appendString appends s as a zero-padded string of n registers
+func appendString(regs []uint16, s string, n int) []uint16 {
+	b := make([]byte, 2*n)
+	copy(b, s)
+	for i := 0; i < n; i++ {
+		regs = append(regs, uint16(b[2*i])<<8|uint16(b[2*i+1]))
+	}
+	return regs
+}
+
+// lookup returns the value of code and whether it is present
+func lookup(values map[string]float64, code string) (float64, bool) {
+	v, ok := values[code]
+	return v, ok
+}
+
+// hasKey reports whether any of the codes has a value
+func hasKey(values map[string]float64, codes ...string) bool {
+	for _, c := range codes {
+		if _, ok := values[c]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ptr returns a pointer to v if ok, else nil
+func ptr(v float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// sum returns the sum of the present values, nil if none is present
+func sum(values [3]*float64) *float64 {
+	var res *float64
+	for _, v := range values {
+		if v != nil {
+			if res == nil {
+				res = new(float64)
+			}
+			*res += *v
+		}
+	}
+	return res
+}
+
+// avg returns the average of the present values, nil if none is present
+func avg(values [3]*float64) *float64 {
+	var n float64
+	for _, v := range values {
+		if v != nil {
+			n++
+		}
+	}
+
+	res := sum(values)
+	if res != nil {
+		*res /= n
+	}
+	return res
+}
@@ -0,0 +1,248 @@
+package sml
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"regexp"
+	"strconv"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// SML type-length field types
+const (
+	typeOctetString = 0x00
+	typeInteger     = 0x50
+	typeUnsigned    = 0x60
+	typeList        = 0x70
+)
+
+// SML message body tags
+const (
+	tagOpenResponse    = 0x0101
+	tagCloseResponse   = 0x0201
+	tagGetListResponse = 0x0701
+)
+
+var (
+	escape     = []byte{0x1b, 0x1b, 0x1b, 0x1b}
+	fileStart  = []byte{0x01, 0x01, 0x01, 0x01}
+	optional   = []byte{0x01} // absent optional field
+	endOfMsg   = []byte{0x00}
+	endOfFile  = byte(0x1a)
+	reDINMeter = regexp.MustCompile(`^([0-9])([A-Z]{3})([0-9]{2})([0-9]{8})$`)
+)
+
+// encoder builds an SML file
+type encoder struct {
+	buf []byte
+}
+
+// tl appends a type-length field. For lists length is the number of
+// elements, otherwise the number of value bytes.
+func (e *encoder) tl(typ byte, length int) {
+	if typ != typeList {
+		length++ // include the TL byte
+		if length > 0x0f {
+			length++ // include the extension byte
+		}
+	}
+
+	if length > 0x0f {
+		e.buf = append(e.buf, 0x80|typ|byte(length>>4), byte(length&0x0f))
+		return
+	}
+	e.buf = append(e.buf, typ|byte(length))
+}
+
+// list appends the header of a list with n elements
+func (e *encoder) list(n int) {
+	e.tl(typeList, n)
+}
+
+// octets appends an octet string
+func (e *encoder) octets(b []byte) {
+	e.tl(typeOctetString, len(b))
+	e.buf = append(e.buf, b...)
+}
+
+// unsigned appends the size least significant bytes of v
+func (e *encoder) unsigned(v uint64, size int) {
+	e.tl(typeUnsigned, size)
+	e.buf = append(e.buf, binary.BigEndian.AppendUint64(nil, v)[8-size:]...)
+}
+
+// integer appends the size least significant bytes of v in two's complement
+func (e *encoder) integer(v int64, size int) {
+	e.tl(typeInteger, size)
+	e.buf = append(e.buf, binary.BigEndian.AppendUint64(nil, uint64(v))[8-size:]...)
+}
+
+// optional appends an absent optional field
+func (e *encoder) optional() {
+	e.buf = append(e.buf, optional...)
+}
+
+// message appends an SML message with the given body
+func (e *encoder) message(transactionID []byte, tag uint32, body func()) {
+	start := len(e.buf)
+
+	e.list(6)
+	e.octets(transactionID)
+	e.unsigned(0, 1) // groupNo
+	e.unsigned(0, 1) // abortOnError
+	e.list(2)
+	e.unsigned(uint64(tag), 4)
+	body()
+
+	e.unsigned(uint64(crc16(e.buf[start:])), 2)
+	e.buf = append(e.buf, endOfMsg...)
+}
+
+// Telegram encodes readings as an SML file with an open response, a list
+// response and a close response, as pushed by the info interface of
+// electricity meters. fileID identifies the file, serverID the meter (see
+// ServerID). Readings that are invalid or have codes that are not OBIS
+// codes, e.g. prefixed by an emhcasa.Aggregate, are omitted.
+func Telegram(fileID uint32, serverID []byte, readings []emhcasa.Reading) []byte {
+	e := &encoder{}
+	e.buf = append(e.buf, escape...)
+	e.buf = append(e.buf, fileStart...)
+
+	reqFileID := binary.BigEndian.AppendUint32(nil, fileID)
+	transaction := func(n byte) []byte { return append(append([]byte(nil), reqFileID...), n) }
+
+	e.message(transaction(0), tagOpenResponse, func() {
+		e.list(6)
+		e.optional() // codepage
+		e.optional() // clientId
+		e.octets(reqFileID)
+		e.octets(serverID)
+		e.optional() // refTime
+		e.optional() // smlVersion
+	})
+
+	var entries []func()
+	for _, r := range readings {
+		if r.Quality == emhcasa.QualityInvalid {
+			continue
+		}
+		code, err := obis.Parse(r.OBIS)
+		if err != nil {
+			continue
+		}
+
+		value, scaler := intValue(r)
+		entries = append(entries, func() {
+			e.list(7)
+			e.octets([]byte{code.A, code.B, code.C, code.D, code.E, code.F})
+			e.optional() // status
+			e.optional() // valTime
+			e.unsigned(uint64(r.Unit), 1)
+			e.integer(int64(scaler), 1)
+			e.integer(value, 8)
+			e.optional() // valueSignature
+		})
+	}
+
+	e.message(transaction(1), tagGetListResponse, func() {
+		e.list(7)
+		e.optional() // clientId
+		e.octets(serverID)
+		e.optional() // listName
+		e.optional() // actSensorTime
+		e.list(len(entries))
+		for _, entry := range entries {
+			entry()
+		}
+		e.optional() // listSignature
+		e.optional() // actGatewayTime
+	})
+
+	e.message(transaction(2), tagCloseResponse, func() {
+		e.list(1)
+		e.optional() // globalSignature
+	})
+
+	e.buf = append(e.buf[:len(escape)+len(fileStart)], escapeData(e.buf[len(escape)+len(fileStart):])...)
+
+	padding := (4 - len(e.buf)%4) % 4
+	e.buf = append(e.buf, make([]byte, padding)...)
+	e.buf = append(e.buf, escape...)
+	e.buf = append(e.buf, endOfFile, byte(padding))
+
+	return binary.BigEndian.AppendUint16(e.buf, crc16(e.buf))
+}
+
+// escapeData doubles escape sequences within the messages
+func escapeData(b []byte) []byte {
+	var res []byte
+	for i := 0; i < len(b); i++ {
+		if bytes.HasPrefix(b[i:], escape) {
+			res = append(res, escape...)
+			res = append(res, escape...)
+			i += len(escape) - 1
+			continue
+		}
+		res = append(res, b[i])
+	}
+	return res
+}
+
+// intValue returns the value of r as integer with a power-of-10 scaler in
+// the unit reported by the gateway, preferring the raw gateway value
+func intValue(r emhcasa.Reading) (int64, int8) {
+	if v, err := strconv.ParseInt(r.RawValue, 10, 64); err == nil && r.Scaler >= -128 && r.Scaler <= 127 {
+		return v, int8(r.Scaler)
+	}
+
+	milli := r.ValueMilli
+	if milli == 0 {
+		milli = int64(math.Round(r.Value * 1000))
+	}
+
+	// thousandths of the unit of Value, which is kilo for energies
+	switch r.Unit {
+	case emhcasa.UnitWattHour, emhcasa.UnitVoltAmpereHour, emhcasa.UnitVarHour:
+		return milli, 0
+	}
+	return milli, -3
+}
+
+// ServerID returns the SML server ID of a meter ID. Meter IDs per
+// DIN 43863-5 such as "1EMH0012345678" are encoded in the 10 byte binary
+// form, other IDs are used as is.
+func ServerID(meterID string) []byte {
+	m := reDINMeter.FindStringSubmatch(meterID)
+	if m == nil {
+		return []byte(meterID)
+	}
+
+	medium, _ := strconv.ParseUint(m[1], 10, 8)
+	version, _ := strconv.ParseUint(m[3], 10, 8)
+	serial, _ := strconv.ParseUint(m[4], 10, 32)
+
+	id := []byte{0x0a, byte(medium)}
+	id = append(id, m[2]...)
+	id = append(id, byte(version))
+	return binary.BigEndian.AppendUint32(id, uint32(serial))
+}
+
+// crc16 returns the CRC-16/X-25 checksum of b in SML byte order
+func crc16(b []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, c := range b {
+		crc ^= uint16(c)
+		for range 8 {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	crc ^= 0xffff
+	return crc<<8 | crc>>8
+}
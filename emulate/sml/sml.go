@@ -0,0 +1,99 @@
+// Package sml re-encodes readings as SML telegrams pushed over TCP.
+//
+// Network IR read heads (e.g. with Tasmota or ser2net) stream the SML
+// telegrams of a meter's info interface on a TCP port. Server emulates such
+// a read head, so tools that only understand SML (vzlogger, Tasmota bridges)
+// can consume the smart meter gateway readings unchanged. Values are sent
+// with the raw value, scaler and unit reported by the gateway.
+package sml
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// WriteTimeout is the time a client has to accept a telegram before it is
+// disconnected.
+var WriteTimeout = 5 * time.Second
+
+// Server pushes an SML telegram to all connected clients on each Update.
+type Server struct {
+	serverID []byte
+
+	mu     sync.Mutex
+	fileID uint32
+	conns  map[net.Conn]struct{}
+}
+
+// New creates a Server sending telegrams for the given meter ID (see ServerID).
+func New(meterID string) *Server {
+	return &Server{serverID: ServerID(meterID), conns: make(map[net.Conn]struct{})}
+}
+
+// Update sends the readings as telegram to all connected clients, e.g. from
+// poll.Poller callbacks. Clients that fail to accept it are disconnected.
+func (s *Server) Update(readings []emhcasa.Reading) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fileID++
+	telegram := Telegram(s.fileID, s.serverID, readings)
+
+	for conn := range s.conns {
+		_ = conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+		if _, err := conn.Write(telegram); err != nil {
+			conn.Close()
+			delete(s.conns, conn)
+		}
+	}
+}
+
+// ListenAndServe listens on the TCP address addr and serves until ctx is
+// cancelled. It returns ctx.Err().
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve accepts clients on l until ctx is cancelled, then closes l and all
+// connections and returns ctx.Err().
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for conn := range s.conns {
+			conn.Close()
+			delete(s.conns, conn)
+		}
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+	}
+}
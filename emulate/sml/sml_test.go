@@ -0,0 +1,140 @@
+package sml
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// TestCRC16 tests the checksum against the CRC-16/X-25 check value
+func TestCRC16(t *testing.T) {
+	if got := crc16([]byte("123456789")); got != 0x6e90 {
+		t.Errorf("crc16() = %04x, want 6e90", got)
+	}
+}
+
+// TestServerID tests encoding of meter IDs
+func TestServerID(t *testing.T) {
+	if got := hex.EncodeToString(ServerID("1EMH0012345678")); got != "0a01454d480000bc614e" {
+		t.Errorf("ServerID() = %s", got)
+	}
+	if got := string(ServerID("meter")); got != "meter" {
+		t.Errorf("ServerID() = %s, want meter", got)
+	}
+}
+
+// TestTelegram tests framing, list entries and checksums of a telegram
+func TestTelegram(t *testing.T) {
+	telegram := Telegram(1, ServerID("1EMH0012345678"), []emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 1234.5678, Unit: emhcasa.UnitWattHour, RawValue: "12345678", Scaler: -1},
+		{OBIS: "16.7.0", Value: -250.5, Unit: emhcasa.UnitWatt},
+		{OBIS: "2.8.0", Value: 1, Quality: emhcasa.QualityInvalid},
+		{OBIS: "pv/1.8.0", Value: 1},
+	})
+
+	if !bytes.HasPrefix(telegram, []byte{0x1b, 0x1b, 0x1b, 0x1b, 1, 1, 1, 1}) {
+		t.Errorf("telegram start = %x", telegram[:8])
+	}
+	if len(telegram)%4 != 0 {
+		t.Errorf("telegram length = %d, want multiple of 4", len(telegram))
+	}
+
+	end := telegram[len(telegram)-8:]
+	if !bytes.Equal(end[:5], []byte{0x1b, 0x1b, 0x1b, 0x1b, 0x1a}) || int(end[5]) > 3 {
+		t.Errorf("telegram end = %x", end)
+	}
+	if got, want := binary.BigEndian.Uint16(end[6:]), crc16(telegram[:len(telegram)-2]); got != want {
+		t.Errorf("file crc = %04x, want %04x", got, want)
+	}
+
+	entries := []string{
+		// objName, status, valTime, unit Wh, scaler -1, value, valueSignature
+		"77" + "070100010800ff" + "01" + "01" + "621e" + "52ff" + "5900000000" + "00bc614e" + "01",
+		// W in thousandths
+		"77" + "070100100700ff" + "01" + "01" + "621b" + "52fd" + "59ffffffff" + "fffc2d7c" + "01",
+	}
+	for _, entry := range entries {
+		if !bytes.Contains(telegram, mustHex(t, entry)) {
+			t.Errorf("telegram missing entry %s:\n%x", entry, telegram)
+		}
+	}
+
+	// the list contains exactly the two entries
+	if !bytes.Contains(telegram, append([]byte{0x01, 0x72}, mustHex(t, entries[0])...)) {
+		t.Errorf("telegram missing two-element value list:\n%x", telegram)
+	}
+}
+
+// TestEscapeData tests doubling of escape sequences in message data
+func TestEscapeData(t *testing.T) {
+	got := escapeData([]byte{1, 0x1b, 0x1b, 0x1b, 0x1b, 2})
+	want := []byte{1, 0x1b, 0x1b, 0x1b, 0x1b, 0x1b, 0x1b, 0x1b, 0x1b, 2}
+	if !bytes.Equal(got, want) {
+		t.Errorf("escapeData() = %x, want %x", got, want)
+	}
+}
+
+// TestServer tests pushing telegrams to connected clients
+func TestServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("1EMH0012345678")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	for range 100 {
+		s.mu.Lock()
+		n := len(s.conns)
+		s.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	readings := []emhcasa.Reading{{OBIS: "16.7.0", Value: 500, Unit: emhcasa.UnitWatt}}
+	s.Update(readings)
+
+	want := Telegram(1, ServerID("1EMH0012345678"), readings)
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("telegram = %x, want %x", got, want)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Serve() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// mustHex decodes a hex string or fails the test
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
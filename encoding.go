@@ -0,0 +1,130 @@
+package emhcasa
+
+import (
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"github.com/iseeberg79/emh-casa-go/internal/protowire"
+)
+
+// Readings can be sent as interface values, e.g. with net/rpc.
+func init() {
+	gob.Register(Reading{})
+	gob.Register([]Reading{})
+}
+
+// MarshalBinary encodes the reading as protobuf message emhcasa.v1.Reading
+// (see proto/emhcasa.proto). It is also used by encoding/gob.
+func (r Reading) MarshalBinary() ([]byte, error) {
+	return r.appendBinary(nil), nil
+}
+
+// appendBinary appends the protobuf encoding of r, omitting zero values
+func (r Reading) appendBinary(b []byte) []byte {
+	if r.OBIS != "" {
+		b = protowire.AppendString(b, 1, r.OBIS)
+	}
+	if r.LogicalName != "" {
+		b = protowire.AppendString(b, 2, r.LogicalName)
+	}
+	if r.Value != 0 {
+		b = protowire.AppendDouble(b, 3, r.Value)
+	}
+	if r.ValueMilli != 0 {
+		b = protowire.AppendVarint(b, 4, uint64(r.ValueMilli))
+	}
+	if r.Unit != 0 {
+		b = protowire.AppendVarint(b, 5, uint64(r.Unit))
+	}
+	if r.RawValue != "" {
+		b = protowire.AppendString(b, 6, r.RawValue)
+	}
+	if r.Scaler != 0 {
+		b = protowire.AppendSint(b, 7, int64(r.Scaler))
+	}
+	if r.StatusRaw != "" {
+		b = protowire.AppendString(b, 8, r.StatusRaw)
+	}
+	if r.Quality != 0 {
+		b = protowire.AppendVarint(b, 9, uint64(r.Quality))
+	}
+	if !r.Timestamp.IsZero() {
+		b = protowire.AppendVarint(b, 10, uint64(r.Timestamp.UnixNano()))
+	}
+	if !r.ReceivedAt.IsZero() {
+		b = protowire.AppendVarint(b, 11, uint64(r.ReceivedAt.UnixNano()))
+	}
+	return b
+}
+
+// UnmarshalBinary decodes a reading encoded by MarshalBinary. Times are
+// returned in the local time zone.
+func (r *Reading) UnmarshalBinary(b []byte) error {
+	*r = Reading{}
+
+	err := protowire.Range(b, func(f protowire.Field) error {
+		switch {
+		case f.Is(1, protowire.Bytes):
+			r.OBIS = string(f.Bytes)
+		case f.Is(2, protowire.Bytes):
+			r.LogicalName = string(f.Bytes)
+		case f.Is(3, protowire.Fixed64):
+			r.Value = f.Double()
+		case f.Is(4, protowire.Varint):
+			r.ValueMilli = f.Int()
+		case f.Is(5, protowire.Varint):
+			r.Unit = Unit(int32(f.Uint))
+		case f.Is(6, protowire.Bytes):
+			r.RawValue = string(f.Bytes)
+		case f.Is(7, protowire.Varint):
+			r.Scaler = int(int32(f.Sint()))
+		case f.Is(8, protowire.Bytes):
+			r.StatusRaw = string(f.Bytes)
+		case f.Is(9, protowire.Varint):
+			r.Quality = Quality(int32(f.Uint))
+		case f.Is(10, protowire.Varint):
+			r.Timestamp = time.Unix(0, f.Int())
+		case f.Is(11, protowire.Varint):
+			r.ReceivedAt = time.Unix(0, f.Int())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("reading: %w", err)
+	}
+
+	return nil
+}
+
+// MarshalReadings encodes readings as protobuf message emhcasa.v1.Readings.
+func MarshalReadings(readings []Reading) []byte {
+	var b []byte
+	for _, r := range readings {
+		b = protowire.AppendBytes(b, 1, r.appendBinary(nil))
+	}
+	return b
+}
+
+// UnmarshalReadings decodes readings encoded by MarshalReadings.
+func UnmarshalReadings(b []byte) ([]Reading, error) {
+	var readings []Reading
+
+	err := protowire.Range(b, func(f protowire.Field) error {
+		if !f.Is(1, protowire.Bytes) {
+			return nil
+		}
+
+		var r Reading
+		if err := r.UnmarshalBinary(f.Bytes); err != nil {
+			return err
+		}
+		readings = append(readings, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return readings, nil
+}
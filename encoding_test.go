@@ -0,0 +1,52 @@
+package emhcasa
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestReadingBinary tests the protobuf and gob round trips of readings
+func TestReadingBinary(t *testing.T) {
+	ts := time.Unix(1700000000, 123)
+	readings := []Reading{
+		{
+			OBIS: "1.8.0", LogicalName: "0100010800FF.255", Value: 1234.567, ValueMilli: 1234567,
+			Unit: UnitWattHour, RawValue: "12345670", Scaler: -1, Timestamp: ts, ReceivedAt: ts.Add(time.Second),
+		},
+		{OBIS: "16.7.0", Value: -500, Unit: UnitWatt, StatusRaw: "0x04", Quality: QualityInvalid},
+		{},
+	}
+
+	got, err := UnmarshalReadings(MarshalReadings(readings))
+	if err != nil {
+		t.Fatalf("UnmarshalReadings() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, readings) {
+		t.Errorf("UnmarshalReadings() = %+v, want %+v", got, readings)
+	}
+
+	b, _ := readings[1].MarshalBinary()
+	want := []byte{0x0a, 6, '1', '6', '.', '7', '.', '0', 0x19, 0, 0, 0, 0, 0, 0x40, 0x7f, 0xc0, 0x28, 27, 0x42, 4, '0', 'x', '0', '4', 0x48, 2}
+	if !bytes.Equal(b, want) {
+		t.Errorf("MarshalBinary() = % x, want % x", b, want)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(map[string]any{"readings": readings}); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	var decoded map[string]any
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded["readings"], readings) {
+		t.Errorf("gob = %+v, want %+v", decoded["readings"], readings)
+	}
+
+	if _, err := UnmarshalReadings([]byte{0x0a, 10, 1}); err == nil {
+		t.Error("UnmarshalReadings() of truncated message succeeded")
+	}
+}
@@ -0,0 +1,155 @@
+// Package evcc adapts a gateway to the meter interfaces of evcc
+// (https://evcc.io), so a smart meter gateway can be used as grid meter.
+//
+// The method sets match api.Meter, api.MeterEnergy, api.PhaseCurrents,
+// api.PhaseVoltages and api.PhasePowers without importing evcc.
+package evcc
+
+import (
+	"errors"
+	"fmt"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// ErrNotAvailable is returned if the gateway does not report a value.
+var ErrNotAvailable = errors.New("value not available")
+
+// Meter implements the evcc meter interfaces on top of a gateway.
+// Each method reads the gateway; wrap it with emhcasa.NewRateLimited, as
+// evcc calls several methods per control cycle.
+type Meter struct {
+	gw     emhcasa.Gateway
+	config emhcasa.PowerConfig
+}
+
+var (
+	_ interface{ CurrentPower() (float64, error) } = (*Meter)(nil)
+	_ interface{ TotalEnergy() (float64, error) }  = (*Meter)(nil)
+	_ interface {
+		Currents() (float64, float64, float64, error)
+	} = (*Meter)(nil)
+	_ interface {
+		Voltages() (float64, float64, float64, error)
+	} = (*Meter)(nil)
+	_ interface {
+		Powers() (float64, float64, float64, error)
+	} = (*Meter)(nil)
+)
+
+// NewMeter creates a Meter normalizing power as configured by config.
+func NewMeter(gw emhcasa.Gateway, config emhcasa.PowerConfig) *Meter {
+	return &Meter{gw: gw, config: config}
+}
+
+// values returns the valid readings of the gateway keyed by OBIS code
+func (m *Meter) values() (map[string]float64, error) {
+	readings, err := m.gw.GetReadings()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64, len(readings))
+	for _, r := range readings {
+		if r.Quality != emhcasa.QualityInvalid {
+			values[r.OBIS] = r.Value
+		}
+	}
+	return values, nil
+}
+
+// CurrentPower implements api.Meter. It returns the active power at the
+// grid connection point in W, positive = import.
+func (m *Meter) CurrentPower() (float64, error) {
+	values, err := m.values()
+	if err != nil {
+		return 0, err
+	}
+
+	if !hasAny(values, obis.PowerImport, obis.PowerExport, obis.Power) {
+		return 0, fmt.Errorf("power: %w", ErrNotAvailable)
+	}
+
+	power, _ := m.config.Normalize(values)
+	return power, nil
+}
+
+// TotalEnergy implements api.MeterEnergy. It returns the imported energy in kWh.
+func (m *Meter) TotalEnergy() (float64, error) {
+	values, err := m.values()
+	if err != nil {
+		return 0, err
+	}
+
+	code := obis.EnergyImport
+	if m.config.Invert {
+		code = obis.EnergyExport
+	}
+
+	energy, ok := values[code]
+	if !ok {
+		return 0, fmt.Errorf("energy: %w", ErrNotAvailable)
+	}
+	return energy, nil
+}
+
+// Currents implements api.PhaseCurrents. It returns the phase currents in A,
+// negative for phases feeding into the grid.
+func (m *Meter) Currents() (float64, float64, float64, error) {
+	return m.phases("currents", []string{obis.CurrentL1, obis.CurrentL2, obis.CurrentL3}, func(p emhcasa.PhaseReading, invert bool) float64 {
+		if power := p.PowerW; invert && power > 0 || !invert && power < 0 {
+			return -p.CurrentA
+		}
+		return p.CurrentA
+	})
+}
+
+// Voltages implements api.PhaseVoltages. It returns the phase voltages in V.
+func (m *Meter) Voltages() (float64, float64, float64, error) {
+	return m.phases("voltages", []string{obis.VoltageL1, obis.VoltageL2, obis.VoltageL3}, func(p emhcasa.PhaseReading, _ bool) float64 {
+		return p.VoltageV
+	})
+}
+
+// Powers implements api.PhasePowers. It returns the phase active powers in W,
+// positive = import.
+func (m *Meter) Powers() (float64, float64, float64, error) {
+	codes := []string{
+		obis.PowerL1, obis.PowerL2, obis.PowerL3,
+		obis.PowerImportL1, obis.PowerImportL2, obis.PowerImportL3,
+		obis.PowerExportL1, obis.PowerExportL2, obis.PowerExportL3,
+	}
+	return m.phases("powers", codes, func(p emhcasa.PhaseReading, invert bool) float64 {
+		if invert {
+			return -p.PowerW
+		}
+		return p.PowerW
+	})
+}
+
+// phases reads the gateway and returns value for each phase, or
+// ErrNotAvailable if none of the codes is reported
+func (m *Meter) phases(name string, codes []string, value func(p emhcasa.PhaseReading, invert bool) float64) (float64, float64, float64, error) {
+	values, err := m.values()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if !hasAny(values, codes...) {
+		return 0, 0, 0, fmt.Errorf("%s: %w", name, ErrNotAvailable)
+	}
+
+	p := emhcasa.Phases(values)
+	return value(p[0], m.config.Invert), value(p[1], m.config.Invert), value(p[2], m.config.Invert), nil
+}
+
+// hasAny reports whether any of the codes has a value
+func hasAny(values map[string]float64, codes ...string) bool {
+	for _, c := range codes {
+		if _, ok := values[c]; ok {
+			return true
+		}
+	}
+	return false
+}
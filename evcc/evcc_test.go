@@ -0,0 +1,88 @@
+package evcc
+
+import (
+	"errors"
+	"testing"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// gatewayFunc adapts a function to the emhcasa.Gateway interface
+type gatewayFunc func() ([]emhcasa.Reading, error)
+
+func (f gatewayFunc) GetReadings() ([]emhcasa.Reading, error) {
+	return f()
+}
+
+// readings returns a gateway reporting the given values
+func readings(values map[string]float64) emhcasa.Gateway {
+	return gatewayFunc(func() ([]emhcasa.Reading, error) {
+		var res []emhcasa.Reading
+		for code, v := range values {
+			res = append(res, emhcasa.Reading{OBIS: code, Value: v})
+		}
+		return res, nil
+	})
+}
+
+// TestMeter tests the evcc meter methods with and without inversion
+func TestMeter(t *testing.T) {
+	gw := readings(map[string]float64{
+		"16.7.0": -1200, "1.8.0": 100, "2.8.0": 20,
+		"36.7.0": 300, "56.7.0": -1500, "76.7.0": 0,
+		"31.7.0": 1.5, "51.7.0": 6.5, "71.7.0": 0,
+		"32.7.0": 230, "52.7.0": 231, "72.7.0": 229,
+	})
+
+	tests := []struct {
+		name   string
+		invert bool
+		power  float64
+		energy float64
+		powers [3]float64
+		curr   [3]float64
+	}{
+		{"normal", false, -1200, 100, [3]float64{300, -1500, 0}, [3]float64{1.5, -6.5, 0}},
+		{"inverted", true, 1200, 20, [3]float64{-300, 1500, 0}, [3]float64{-1.5, 6.5, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMeter(gw, emhcasa.PowerConfig{Invert: tt.invert})
+
+			if p, err := m.CurrentPower(); err != nil || p != tt.power {
+				t.Errorf("CurrentPower() = %v, %v, want %v", p, err, tt.power)
+			}
+			if e, err := m.TotalEnergy(); err != nil || e != tt.energy {
+				t.Errorf("TotalEnergy() = %v, %v, want %v", e, err, tt.energy)
+			}
+			if l1, l2, l3, err := m.Powers(); err != nil || [3]float64{l1, l2, l3} != tt.powers {
+				t.Errorf("Powers() = %v %v %v, %v, want %v", l1, l2, l3, err, tt.powers)
+			}
+			if l1, l2, l3, err := m.Currents(); err != nil || [3]float64{l1, l2, l3} != tt.curr {
+				t.Errorf("Currents() = %v %v %v, %v, want %v", l1, l2, l3, err, tt.curr)
+			}
+			if l1, l2, l3, err := m.Voltages(); err != nil || [3]float64{l1, l2, l3} != [3]float64{230, 231, 229} {
+				t.Errorf("Voltages() = %v %v %v, %v", l1, l2, l3, err)
+			}
+		})
+	}
+}
+
+// TestMeterNotAvailable tests errors for missing values and gateway failures
+func TestMeterNotAvailable(t *testing.T) {
+	m := NewMeter(readings(map[string]float64{"1.8.0": 100}), emhcasa.PowerConfig{})
+
+	if _, err := m.CurrentPower(); !errors.Is(err, ErrNotAvailable) {
+		t.Errorf("CurrentPower() error = %v, want %v", err, ErrNotAvailable)
+	}
+	if _, _, _, err := m.Currents(); !errors.Is(err, ErrNotAvailable) {
+		t.Errorf("Currents() error = %v, want %v", err, ErrNotAvailable)
+	}
+
+	failing := errors.New("gateway unavailable")
+	m = NewMeter(gatewayFunc(func() ([]emhcasa.Reading, error) { return nil, failing }), emhcasa.PowerConfig{})
+	if _, err := m.TotalEnergy(); !errors.Is(err, failing) {
+		t.Errorf("TotalEnergy() error = %v, want %v", err, failing)
+	}
+}
@@ -0,0 +1,125 @@
+// Package csv writes readings and stored history as CSV for spreadsheets and
+// data analysis tools.
+//
+// Readings are written in long format, one row per reading:
+//
+//	time,obis,description,value,unit,raw_value,scaler,quality
+//
+// with time being the capture time in RFC 3339 and value and unit as in
+// emhcasa.Reading.Value (energies in kWh). Series are written in wide format
+// by WriteSeries, one column per series.
+package csv
+
+import (
+	stdcsv "encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+	"github.com/iseeberg79/emh-casa-go/store"
+)
+
+// header is the column layout of Writer
+var header = []string{"time", "obis", "description", "value", "unit", "raw_value", "scaler", "quality"}
+
+// Writer writes readings as CSV rows, preceded by a header row.
+type Writer struct {
+	w      *stdcsv.Writer
+	header bool
+}
+
+// NewWriter creates a Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: stdcsv.NewWriter(w)}
+}
+
+// Write writes one row per reading and flushes, e.g. from poll.Poller callbacks.
+func (w *Writer) Write(readings []emhcasa.Reading) error {
+	if !w.header {
+		if err := w.w.Write(header); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	for _, r := range readings {
+		err := w.w.Write([]string{
+			formatTime(r.Timestamp),
+			r.OBIS,
+			obis.Description(r.OBIS),
+			formatFloat(r.Value),
+			r.ValueUnit(),
+			r.RawValue,
+			strconv.Itoa(r.Scaler),
+			r.Quality.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// WriteSeries writes series side by side with a time column and one
+// "obis (unit)" column per series. Rows are the union of all timestamps;
+// cells of series without value at a timestamp are empty.
+func WriteSeries(w io.Writer, series ...store.Series) error {
+	var times []time.Time
+	values := make([]map[time.Time]float64, len(series))
+
+	row := []string{"time"}
+	for i, s := range series {
+		row = append(row, s.OBIS+" ("+s.Unit+")")
+
+		values[i] = make(map[time.Time]float64, len(s.Values))
+		for _, v := range s.Values {
+			t := v.Time.UTC()
+			times = append(times, t)
+			values[i][t] = v.Value
+		}
+	}
+
+	slices.SortFunc(times, time.Time.Compare)
+	times = slices.Compact(times)
+
+	cw := stdcsv.NewWriter(w)
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+
+	for _, t := range times {
+		row = append(row[:0], formatTime(t))
+		for i := range series {
+			cell := ""
+			if v, ok := values[i][t]; ok {
+				cell = formatFloat(v)
+			}
+			row = append(row, cell)
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatTime formats t in RFC 3339, empty if zero
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatFloat formats v with the minimum number of digits
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
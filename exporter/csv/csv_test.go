@@ -0,0 +1,60 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/store"
+)
+
+// TestWriter tests the column layout of readings
+func TestWriter(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var b strings.Builder
+	w := NewWriter(&b)
+
+	err := w.Write([]emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 1234.5678, Unit: emhcasa.UnitWattHour, RawValue: "12345678", Scaler: -1, Timestamp: ts},
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	err = w.Write([]emhcasa.Reading{
+		{OBIS: "16.7.0", Value: -250, Unit: emhcasa.UnitWatt, RawValue: "-250", Quality: emhcasa.QualityInvalid, Timestamp: ts},
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := "time,obis,description,value,unit,raw_value,scaler,quality\n" +
+		"2026-03-01T12:00:00Z,1.8.0,Total Energy Import,1234.5678,kWh,12345678,-1,good\n" +
+		"2026-03-01T12:00:00Z,16.7.0,Current Power (Active),-250,W,-250,0,invalid\n"
+	if b.String() != want {
+		t.Errorf("Write() = %q, want %q", b.String(), want)
+	}
+}
+
+// TestWriteSeries tests merging series into columns
+func TestWriteSeries(t *testing.T) {
+	at := func(m int) time.Time { return time.Date(2026, 3, 1, 12, m, 0, 0, time.UTC) }
+
+	imp := store.Series{OBIS: "1.8.0", Unit: "kWh", Values: []store.TimedValue{{Time: at(0), Value: 100}, {Time: at(15), Value: 100.5}}}
+	exp := store.Series{OBIS: "2.8.0", Unit: "kWh", Values: []store.TimedValue{{Time: at(15), Value: 20}, {Time: at(30), Value: 21}}}
+
+	var b strings.Builder
+	if err := WriteSeries(&b, imp, exp); err != nil {
+		t.Fatalf("WriteSeries() error = %v", err)
+	}
+
+	want := "time,1.8.0 (kWh),2.8.0 (kWh)\n" +
+		"2026-03-01T12:00:00Z,100,\n" +
+		"2026-03-01T12:15:00Z,100.5,20\n" +
+		"2026-03-01T12:30:00Z,,21\n"
+	if b.String() != want {
+		t.Errorf("WriteSeries() = %q, want %q", b.String(), want)
+	}
+}
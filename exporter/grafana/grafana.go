@@ -0,0 +1,121 @@
+// Package grafana streams readings to Grafana Live
+// (https://grafana.com/docs/grafana/latest/setup-grafana/set-up-grafana-live/)
+// for dashboards that update with every poll, without a time series database
+// in between.
+//
+// Readings are pushed in the Influx line protocol to the HTTP push endpoint
+// /api/live/push/{stream}. Each gateway becomes one measurement "smgw" line
+// tagged with the gateway name and one field per OBIS code, which Grafana
+// publishes on the channel stream/{stream}/smgw. Panels subscribe to it with
+// the "-- Grafana --" data source and "Live Measurements".
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// measurement is the name of the pushed measurement and last channel segment
+const measurement = "smgw"
+
+// Pusher pushes readings to a Grafana Live stream.
+type Pusher struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// New creates a Pusher for the Grafana URL (e.g. "http://localhost:3000"),
+// stream ID and service account token with the Editor role.
+func New(grafana, stream, token string) *Pusher {
+	return &Pusher{
+		url:    strings.TrimSuffix(grafana, "/") + "/api/live/push/" + url.PathEscape(stream),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used for requests.
+func (p *Pusher) SetHTTPClient(client *http.Client) {
+	p.client = client
+}
+
+// Push sends the readings of a gateway as one line stamped with the latest
+// Reading.Timestamp. Readings of invalid quality are skipped.
+func (p *Pusher) Push(ctx context.Context, gateway string, readings []emhcasa.Reading) error {
+	line := Line(gateway, readings)
+	if line == nil {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("grafana live: unexpected status code: %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
+
+// Line encodes the readings of a gateway as Influx line protocol, e.g.
+// "smgw,gateway=home 1.8.0=1234.5,16.7.0=500 1700000000000000000". It
+// returns nil if there are no valid readings.
+func Line(gateway string, readings []emhcasa.Reading) []byte {
+	var (
+		fields []string
+		ts     time.Time
+	)
+
+	for _, r := range readings {
+		if r.Quality == emhcasa.QualityInvalid {
+			continue
+		}
+
+		fields = append(fields, escape(r.OBIS)+"="+strconv.FormatFloat(r.Value, 'f', -1, 64))
+		if r.Timestamp.After(ts) {
+			ts = r.Timestamp
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	return fmt.Appendf(nil, "%s,gateway=%s %s %d\n", measurement, escape(gateway), strings.Join(fields, ","), ts.UnixNano())
+}
+
+// escaper escapes tag values and field keys
+var escaper = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, `\`, `\\`)
+
+// escape escapes a tag value or field key
+func escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,71 @@
+package grafana
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// TestLine tests the line protocol encoding
+func TestLine(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name     string
+		gateway  string
+		readings []emhcasa.Reading
+		want     string
+	}{
+		{"fields", "home", []emhcasa.Reading{
+			{OBIS: "1.8.0", Value: 1234.5, Timestamp: ts.Add(-time.Second)},
+			{OBIS: "16.7.0", Value: -500, Timestamp: ts},
+			{OBIS: "2.8.0", Value: 1, Quality: emhcasa.QualityInvalid},
+		}, "smgw,gateway=home 1.8.0=1234.5,16.7.0=-500 1700000000000000000\n"},
+		{"escaping", "my home,1", []emhcasa.Reading{
+			{OBIS: "sum:16.7.0", Value: 1, Timestamp: ts},
+		}, "smgw,gateway=my\\ home\\,1 sum:16.7.0=1 1700000000000000000\n"},
+		{"empty", "home", []emhcasa.Reading{
+			{OBIS: "1.8.0", Quality: emhcasa.QualityInvalid},
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := string(Line(tt.gateway, tt.readings)); got != tt.want {
+			t.Errorf("%s: Line() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestPush tests the push request and error handling
+func TestPush(t *testing.T) {
+	var path, auth, body string
+	status := http.StatusOK
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		path, auth, body = r.URL.Path, r.Header.Get("Authorization"), string(b)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL+"/", "smartmeter", "glsa_token")
+	readings := []emhcasa.Reading{{OBIS: "16.7.0", Value: 500, Timestamp: time.Unix(1, 0)}}
+
+	if err := p.Push(context.Background(), "home", readings); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if path != "/api/live/push/smartmeter" || auth != "Bearer glsa_token" || body != "smgw,gateway=home 16.7.0=500 1000000000\n" {
+		t.Errorf("request = %s %q %q", path, auth, body)
+	}
+
+	status = http.StatusUnauthorized
+	if err := p.Push(context.Background(), "home", readings); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Push() error = %v, want status error", err)
+	}
+}
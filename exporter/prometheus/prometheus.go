@@ -0,0 +1,219 @@
+// Package prometheus exposes gateway readings as Prometheus metrics.
+//
+// Metrics are written in the Prometheus text exposition format, so no client
+// library is required. Metric names and HELP texts are derived from the obis
+// registry, e.g. 1.8.0 becomes smgw_active_energy_import_kilowatt_hours_total.
+// Exporter serves them for scraping, RemoteWriter pushes them via remote write.
+package prometheus
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+	"github.com/iseeberg79/emh-casa-go/server"
+)
+
+const namespace = "smgw"
+
+// Exporter is an http.Handler reading all gateways on each scrape.
+// Gateways are read sequentially; wrap them with emhcasa.NewRateLimited if
+// they are scraped by several Prometheus servers.
+type Exporter struct {
+	gateways map[string]emhcasa.Gateway
+	auth     server.Auth
+	tls      *tls.Config
+}
+
+// New creates an Exporter for the given gateways, keyed by the name used as
+// gateway label.
+func New(gateways map[string]emhcasa.Gateway) *Exporter {
+	return &Exporter{gateways: gateways}
+}
+
+// ServeHTTP implements http.Handler.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = e.Write(w)
+}
+
+// SetAuth requires scrapers of ListenAndServe to authenticate, e.g. with
+// the authorization or basic_auth settings of the scrape config.
+func (e *Exporter) SetAuth(auth server.Auth) {
+	e.auth = auth
+}
+
+// SetTLSConfig makes ListenAndServe serve HTTPS with the certificates of
+// config; set scheme: https in the scrape config.
+func (e *Exporter) SetTLSConfig(config *tls.Config) {
+	e.tls = config
+}
+
+// ListenAndServe serves the metrics at /metrics on addr until ctx is
+// cancelled and returns ctx.Err().
+func (e *Exporter) ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", server.RequireAuth(e, e.auth))
+
+	return server.ListenAndServeTLS(ctx, addr, mux, e.tls)
+}
+
+// family is a metric with its samples
+type family struct {
+	help, typ string
+	samples   []string
+}
+
+// Write reads all gateways and writes their readings in the text exposition
+// format. A failing gateway is reported by smgw_up and does not affect the
+// others. Readings of invalid quality are omitted.
+func (e *Exporter) Write(w io.Writer) error {
+	families := map[string]*family{
+		namespace + "_up": {help: "Whether the last read of the gateway succeeded.", typ: "gauge"},
+	}
+
+	add := func(name, help, typ string, labels [][2]string, value float64) {
+		f, ok := families[name]
+		if !ok {
+			f = &family{help: help, typ: typ}
+			families[name] = f
+		}
+		f.samples = append(f.samples, name+formatLabels(labels)+" "+strconv.FormatFloat(value, 'g', -1, 64))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(e.gateways)) {
+		gw := e.gateways[name]
+
+		readings, err := gw.GetReadings()
+		if err != nil {
+			add(namespace+"_up", "", "", [][2]string{{"gateway", name}}, 0)
+			continue
+		}
+		add(namespace+"_up", "", "", [][2]string{{"gateway", name}}, 1)
+
+		var meter string
+		if m, ok := gw.(interface{ MeterID() (string, error) }); ok {
+			meter, _ = m.MeterID()
+		}
+
+		for _, r := range readings {
+			if r.Quality == emhcasa.QualityInvalid {
+				continue
+			}
+
+			metric, help, typ, labels := describe(r)
+			add(metric, help, typ, append([][2]string{{"gateway", name}, {"meter", meter}}, labels...), r.Value)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(families)) {
+		f := families[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.typ); err != nil {
+			return err
+		}
+		for _, s := range f.samples {
+			if _, err := io.WriteString(w, s+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// describe returns the metric name, HELP text, type and obis/phase labels of
+// a reading, e.g. smgw_active_power_import_watts for 1.7.0. Registers without
+// registry entry are described as smgw_register with a unit label.
+func describe(r emhcasa.Reading) (name, help, typ string, labels [][2]string) {
+	labels = [][2]string{{"obis", r.OBIS}}
+
+	e, ok := obis.Lookup(r.OBIS)
+	if !ok {
+		labels = append(labels, [2]string{"unit", r.ValueUnit()})
+		return namespace + "_register", "Register without obis registry entry.", "gauge", labels
+	}
+
+	if e.Phase > 0 {
+		labels = append(labels, [2]string{"phase", "L" + strconv.Itoa(e.Phase)})
+	}
+
+	name = namespace + "_" + strings.ReplaceAll(string(e.Quantity), " ", "_")
+	help = strings.ToUpper(string(e.Quantity[:1])) + string(e.Quantity[1:])
+
+	switch e.Direction {
+	case obis.DirectionImport, obis.DirectionExport:
+		name += "_" + string(e.Direction)
+		help += " " + string(e.Direction)
+	case obis.DirectionNet:
+		help += ", positive = import"
+	}
+
+	if suffix := unitSuffix(r.Unit); suffix != "" {
+		name += "_" + suffix
+	}
+
+	typ = "gauge"
+	if e.Category == obis.CategoryCumulative {
+		name += "_total"
+		typ = "counter"
+	}
+
+	if e.Unit != "" {
+		help += " (" + e.Unit + ")"
+	}
+
+	return name, help + ".", typ, labels
+}
+
+// unitSuffix returns the metric name suffix of the unit of Reading.Value
+func unitSuffix(u emhcasa.Unit) string {
+	switch u {
+	case emhcasa.UnitWatt:
+		return "watts"
+	case emhcasa.UnitVoltAmpere:
+		return "volt_amperes"
+	case emhcasa.UnitVar:
+		return "vars"
+	case emhcasa.UnitWattHour:
+		return "kilowatt_hours"
+	case emhcasa.UnitVoltAmpereHour:
+		return "kilovolt_ampere_hours"
+	case emhcasa.UnitVarHour:
+		return "kilovar_hours"
+	case emhcasa.UnitAmpere:
+		return "amperes"
+	case emhcasa.UnitVolt:
+		return "volts"
+	case emhcasa.UnitHertz:
+		return "hertz"
+	case emhcasa.UnitCubicMetre:
+		return "cubic_meters"
+	case emhcasa.UnitCelsius:
+		return "celsius"
+	case emhcasa.UnitPercent:
+		return "percent"
+	case emhcasa.UnitDimensionless:
+		return "ratio"
+	}
+	return ""
+}
+
+// labelEscaper escapes label values as required by the exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels formats label pairs as {name="value",...}
+func formatLabels(labels [][2]string) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l[0] + `="` + labelEscaper.Replace(l[1]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,88 @@
+package prometheus
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// gatewayFunc adapts a function to the emhcasa.Gateway interface
+type gatewayFunc func() ([]emhcasa.Reading, error)
+
+func (f gatewayFunc) GetReadings() ([]emhcasa.Reading, error) {
+	return f()
+}
+
+// meterGateway is a gateway reporting a meter ID
+type meterGateway struct {
+	gatewayFunc
+}
+
+func (meterGateway) MeterID() (string, error) {
+	return "1EMH0012345678", nil
+}
+
+// TestExporter tests metric names, labels and types of exported readings
+func TestExporter(t *testing.T) {
+	home := meterGateway{func() ([]emhcasa.Reading, error) {
+		return []emhcasa.Reading{
+			{OBIS: "1.8.0", Value: 1234.5, Unit: emhcasa.UnitWattHour},
+			{OBIS: "36.7.0", Value: -200, Unit: emhcasa.UnitWatt},
+			{OBIS: "16.7.0", Value: 9999, Unit: emhcasa.UnitWatt, Quality: emhcasa.QualityInvalid},
+			{OBIS: "96.1.0", Value: 7, Unit: emhcasa.Unit(0)},
+		}, nil
+	}}
+	broken := gatewayFunc(func() ([]emhcasa.Reading, error) {
+		return nil, errors.New("gateway unavailable")
+	})
+
+	srv := httptest.NewServer(New(map[string]emhcasa.Gateway{"home": home, "broken": broken}))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	got := string(body)
+
+	for _, want := range []string{
+		"# HELP smgw_active_energy_import_kilowatt_hours_total Active energy import (kWh).\n" +
+			"# TYPE smgw_active_energy_import_kilowatt_hours_total counter\n" +
+			`smgw_active_energy_import_kilowatt_hours_total{gateway="home",meter="1EMH0012345678",obis="1.8.0"} 1234.5` + "\n",
+		"# TYPE smgw_active_power_watts gauge\n" +
+			`smgw_active_power_watts{gateway="home",meter="1EMH0012345678",obis="36.7.0",phase="L1"} -200` + "\n",
+		`smgw_register{gateway="home",meter="1EMH0012345678",obis="96.1.0",unit="Unit(0)"} 7` + "\n",
+		`smgw_up{gateway="broken"} 0` + "\n",
+		`smgw_up{gateway="home"} 1` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metrics missing %q in\n%s", want, got)
+		}
+	}
+
+	if strings.Contains(got, `obis="16.7.0"`) {
+		t.Errorf("metrics contain invalid reading:\n%s", got)
+	}
+}
+
+// TestFormatLabels tests escaping of label values
+func TestFormatLabels(t *testing.T) {
+	got := formatLabels([][2]string{{"gateway", "a\"b\\c\nd"}})
+	if want := `{gateway="a\"b\\c\nd"}`; got != want {
+		t.Errorf("formatLabels() = %s, want %s", got, want)
+	}
+}
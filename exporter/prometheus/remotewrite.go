@@ -0,0 +1,141 @@
+package prometheus
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/internal/protowire"
+)
+
+// RemoteWriter pushes readings to a Prometheus remote write endpoint
+// (Prometheus, VictoriaMetrics, Mimir, Grafana Cloud), for pollers that
+// cannot be scraped, e.g. behind NAT. Samples carry the gateway capture time.
+// Metric names and labels are the same as served by Exporter, without the
+// meter label.
+type RemoteWriter struct {
+	url      string
+	client   *http.Client
+	user     string
+	password string
+}
+
+// NewRemoteWriter creates a RemoteWriter for the remote write URL, e.g.
+// "http://localhost:9090/api/v1/write".
+func NewRemoteWriter(url string) *RemoteWriter {
+	return &RemoteWriter{url: url, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// SetHTTPClient replaces the HTTP client used for requests.
+func (w *RemoteWriter) SetHTTPClient(client *http.Client) {
+	w.client = client
+}
+
+// SetBasicAuth sets credentials for endpoints requiring basic authentication.
+func (w *RemoteWriter) SetBasicAuth(user, password string) {
+	w.user, w.password = user, password
+}
+
+// Push sends the readings of a gateway as one remote write request.
+// Readings of invalid quality are skipped.
+func (w *RemoteWriter) Push(ctx context.Context, gateway string, readings []emhcasa.Reading) error {
+	var series [][]byte
+	for _, r := range readings {
+		if r.Quality == emhcasa.QualityInvalid {
+			continue
+		}
+
+		name, _, _, labels := describe(r)
+		labels = append(labels, [2]string{"__name__", name}, [2]string{"gateway", gateway})
+		slices.SortFunc(labels, func(a, b [2]string) int { return cmp.Compare(a[0], b[0]) })
+
+		ts := r.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+
+		series = append(series, timeSeries(labels, r.Value, ts))
+	}
+
+	if len(series) == 0 {
+		return nil
+	}
+
+	var body []byte
+	for _, s := range series {
+		body = protowire.AppendBytes(body, 1, s) // WriteRequest.timeseries
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(snappyEncode(body)))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.user != "" {
+		req.SetBasicAuth(w.user, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("remote write: unexpected status code: %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// timeSeries encodes a prometheus.TimeSeries protobuf message with one sample
+func timeSeries(labels [][2]string, value float64, ts time.Time) []byte {
+	var b []byte
+	for _, l := range labels {
+		var label []byte
+		label = protowire.AppendString(label, 1, l[0]) // Label.name
+		label = protowire.AppendString(label, 2, l[1]) // Label.value
+		b = protowire.AppendBytes(b, 1, label)         // TimeSeries.labels
+	}
+
+	var sample []byte
+	sample = protowire.AppendDouble(sample, 1, value)                  // Sample.value
+	sample = protowire.AppendVarint(sample, 2, uint64(ts.UnixMilli())) // Sample.timestamp
+
+	return protowire.AppendBytes(b, 2, sample) // TimeSeries.samples
+}
+
+// snappyEncode encodes b in the snappy block format using literals only.
+// Remote write payloads are small, so compression is not worth a dependency.
+func snappyEncode(b []byte) []byte {
+	res := binary.AppendUvarint(nil, uint64(len(b)))
+
+	for len(b) > 0 {
+		n := min(len(b), 1<<16)
+
+		switch {
+		case n <= 60:
+			res = append(res, byte(n-1)<<2)
+		case n <= 1<<8:
+			res = append(res, 60<<2, byte(n-1))
+		default:
+			res = append(res, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+
+		res = append(res, b[:n]...)
+		b = b[n:]
+	}
+
+	return res
+}
@@ -0,0 +1,168 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// snappyDecode decodes snappy blocks consisting of literals
+func snappyDecode(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	size, n := binary.Uvarint(b)
+	b = b[n:]
+
+	var res []byte
+	for len(b) > 0 {
+		tag := b[0]
+		if tag&3 != 0 {
+			t.Fatalf("unexpected snappy tag %02x", tag)
+		}
+
+		length, hdr := int(tag>>2)+1, 1
+		switch tag >> 2 {
+		case 60:
+			length, hdr = int(b[1])+1, 2
+		case 61:
+			length, hdr = int(b[1])|int(b[2])<<8+1, 3
+		}
+
+		res = append(res, b[hdr:hdr+length]...)
+		b = b[hdr+length:]
+	}
+
+	if len(res) != int(size) {
+		t.Fatalf("snappy length = %d, want %d", len(res), size)
+	}
+	return res
+}
+
+// fields splits a protobuf message into its fields
+func fields(t *testing.T, b []byte) (res []struct {
+	num  int
+	data []byte
+	val  uint64
+}) {
+	t.Helper()
+
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+
+		f := struct {
+			num  int
+			data []byte
+			val  uint64
+		}{num: int(key >> 3)}
+
+		switch key & 7 {
+		case 0:
+			f.val, n = binary.Uvarint(b)
+			b = b[n:]
+		case 1:
+			f.val = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			f.data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+
+		res = append(res, f)
+	}
+	return res
+}
+
+// TestRemoteWriter tests the remote write request encoding
+func TestRemoteWriter(t *testing.T) {
+	var body []byte
+	var header http.Header
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	rw := NewRemoteWriter(srv.URL)
+	rw.SetBasicAuth("user", "secret")
+
+	ts := time.UnixMilli(1700000000123)
+	err := rw.Push(context.Background(), "home", []emhcasa.Reading{
+		{OBIS: "36.7.0", Value: -250.5, Unit: emhcasa.UnitWatt, Timestamp: ts},
+		{OBIS: "16.7.0", Value: 9999, Unit: emhcasa.UnitWatt, Quality: emhcasa.QualityInvalid},
+	})
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	if header.Get("Content-Encoding") != "snappy" || header.Get("Content-Type") != "application/x-protobuf" ||
+		header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Errorf("headers = %v", header)
+	}
+	if user, pass, ok := (&http.Request{Header: header}).BasicAuth(); !ok || user != "user" || pass != "secret" {
+		t.Errorf("basic auth = %q %q %v", user, pass, ok)
+	}
+
+	series := fields(t, snappyDecode(t, body))
+	if len(series) != 1 || series[0].num != 1 {
+		t.Fatalf("write request = %+v, want one time series", series)
+	}
+
+	var labels []string
+	for _, f := range fields(t, series[0].data) {
+		switch f.num {
+		case 1:
+			l := fields(t, f.data)
+			labels = append(labels, string(l[0].data)+"="+string(l[1].data))
+		case 2:
+			s := fields(t, f.data)
+			if v := math.Float64frombits(s[0].val); v != -250.5 || s[1].val != 1700000000123 {
+				t.Errorf("sample = %v @ %d", v, s[1].val)
+			}
+		}
+	}
+
+	want := "__name__=smgw_active_power_watts,gateway=home,obis=36.7.0,phase=L1"
+	if got := strings.Join(labels, ","); got != want {
+		t.Errorf("labels = %s, want %s", got, want)
+	}
+}
+
+// TestRemoteWriterStatus tests error reporting of rejected requests
+func TestRemoteWriterStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	err := NewRemoteWriter(srv.URL).Push(context.Background(), "home", []emhcasa.Reading{{OBIS: "1.8.0", Value: 1}})
+	if err == nil || !strings.Contains(err.Error(), "400: out of order sample") {
+		t.Errorf("Push() error = %v", err)
+	}
+}
+
+// TestSnappyEncode tests literal encoding across length classes
+func TestSnappyEncode(t *testing.T) {
+	for _, n := range []int{0, 1, 60, 61, 256, 257, 1 << 16, 1<<16 + 1, 200000} {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(i)
+		}
+		if got := snappyDecode(t, snappyEncode(b)); string(got) != string(b) {
+			t.Errorf("snappy round trip of %d bytes failed", n)
+		}
+	}
+}
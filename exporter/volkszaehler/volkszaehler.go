@@ -0,0 +1,115 @@
+// Package volkszaehler pushes readings to a Volkszähler middleware
+// (https://volkszaehler.org) for long-term storage.
+//
+// Each OBIS code is mapped to a channel UUID. Values are pushed in the units
+// of emhcasa.Reading.Value, so energy counters arrive in kWh: configure
+// their channels (e.g. "El. Energie (Zählerstände)") with resolution 1.
+package volkszaehler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// Pusher pushes readings to the channels of a Volkszähler middleware.
+type Pusher struct {
+	middleware string
+	channels   map[string]string
+	client     *http.Client
+
+	mu     sync.Mutex
+	pushed map[string]time.Time // timestamp of the last tuple per channel
+}
+
+// New creates a Pusher for the middleware URL (e.g.
+// "http://localhost/middleware.php") and channel UUIDs keyed by OBIS code.
+func New(middleware string, channels map[string]string) *Pusher {
+	return &Pusher{
+		middleware: strings.TrimSuffix(middleware, "/"),
+		channels:   channels,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		pushed:     make(map[string]time.Time),
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used for requests.
+func (p *Pusher) SetHTTPClient(client *http.Client) {
+	p.client = client
+}
+
+// Push adds the readings of all mapped OBIS codes to their channels, stamped
+// with Reading.Timestamp. Readings of invalid quality, unmapped codes and
+// readings not newer than the last tuple pushed to their channel, e.g. while
+// the gateway hasn't updated its capture time, are skipped. Failing channels
+// don't stop the others; their errors are joined.
+func (p *Pusher) Push(ctx context.Context, readings []emhcasa.Reading) error {
+	var errs []error
+
+	for _, r := range readings {
+		uuid, ok := p.channels[r.OBIS]
+		if !ok || r.Quality == emhcasa.QualityInvalid {
+			continue
+		}
+
+		ts := r.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+
+		p.mu.Lock()
+		last := p.pushed[uuid]
+		p.mu.Unlock()
+
+		if !ts.After(last) {
+			continue
+		}
+
+		if err := p.add(ctx, uuid, ts, r.Value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.OBIS, err))
+			continue
+		}
+
+		p.mu.Lock()
+		p.pushed[uuid] = ts
+		p.mu.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// add adds a tuple to a channel
+func (p *Pusher) add(ctx context.Context, uuid string, ts time.Time, value float64) error {
+	q := url.Values{
+		"ts":    {strconv.FormatInt(ts.UnixMilli(), 10)},
+		"value": {strconv.FormatFloat(value, 'f', -1, 64)},
+	}
+	uri := fmt.Sprintf("%s/data/%s.json?%s", p.middleware, url.PathEscape(uuid), q.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
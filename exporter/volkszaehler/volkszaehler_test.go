@@ -0,0 +1,79 @@
+package volkszaehler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// TestPush tests channel mapping, tuple encoding and error handling
+func TestPush(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.String())
+		mu.Unlock()
+
+		if strings.Contains(r.URL.Path, "broken") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"version":"0.3","rows":1}`))
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL+"/middleware.php/", map[string]string{
+		"1.8.0":  "uuid-import",
+		"16.7.0": "uuid-power",
+		"2.8.0":  "broken",
+	})
+
+	ts := time.UnixMilli(1700000000123)
+	err := p.Push(context.Background(), []emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 1234.5, Timestamp: ts},
+		{OBIS: "16.7.0", Value: 9999, Quality: emhcasa.QualityInvalid, Timestamp: ts},
+		{OBIS: "36.7.0", Value: 100, Timestamp: ts},
+		{OBIS: "2.8.0", Value: 20, Timestamp: ts},
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "2.8.0: unexpected status code: 400") {
+		t.Errorf("Push() error = %v, want status error for 2.8.0", err)
+	}
+
+	want := []string{
+		"POST /middleware.php/data/uuid-import.json?ts=1700000000123&value=1234.5",
+		"POST /middleware.php/data/broken.json?ts=1700000000123&value=20",
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+
+	// unchanged capture time: only the failed tuple is sent again
+	requests = nil
+	later := ts.Add(time.Second)
+	_ = p.Push(context.Background(), []emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 1234.5, Timestamp: ts},
+		{OBIS: "2.8.0", Value: 20, Timestamp: ts},
+	})
+	_ = p.Push(context.Background(), []emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 1234.6, Timestamp: later},
+	})
+
+	want = []string{
+		"POST /middleware.php/data/broken.json?ts=1700000000123&value=20",
+		"POST /middleware.php/data/uuid-import.json?ts=1700000001123&value=1234.6",
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests of repeated pushes = %q, want %q", requests, want)
+	}
+}
@@ -6,8 +6,9 @@ require github.com/jpfielding/go-http-digest v0.0.0-20240123121450-cffc47d5d6d8
 
 require (
 	github.com/pion/logging v0.2.4 // indirect
-	github.com/pion/mdns/v2 v2.1.0 // indirect
+	github.com/pion/mdns/v2 v2.1.0
 	github.com/tobima/smgw-discover-go v0.0.2
-	golang.org/x/net v0.49.0 // indirect
+	golang.org/x/net v0.49.0
 	golang.org/x/sys v0.40.0 // indirect
+	golang.org/x/text v0.33.0 // indirect
 )
@@ -0,0 +1,113 @@
+// Package homeassistant maps OBIS registers to Home Assistant sensor
+// metadata, so readings published to Home Assistant (MQTT discovery, REST
+// sensors) get long-term statistics and show up in the energy dashboard.
+//
+// Energy and volume counters are total_increasing, all other registers
+// measurements. Device classes and units follow the obis registry, entity
+// names its descriptions.
+package homeassistant
+
+import (
+	"strings"
+
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// Home Assistant sensor state classes
+const (
+	StateClassMeasurement     = "measurement"
+	StateClassTotalIncreasing = "total_increasing"
+)
+
+// Entity describes the Home Assistant sensor of an OBIS register.
+type Entity struct {
+	OBIS              string `json:"obis"`
+	UniqueID          string `json:"unique_id"` // e.g. "smgw_1emh0012345678_1_8_0"
+	ObjectID          string `json:"object_id"` // e.g. "smgw_1emh0012345678_total_energy_import"
+	Name              string `json:"name"`      // e.g. "Total Energy Import"
+	DeviceClass       string `json:"device_class,omitempty"`
+	StateClass        string `json:"state_class"`
+	UnitOfMeasurement string `json:"unit_of_measurement,omitempty"`
+}
+
+// NewEntity returns the entity of an OBIS code for a device, usually the
+// meter ID, which makes the IDs unique across meters. It returns false for
+// codes unknown to the obis registry.
+func NewEntity(device, code string) (Entity, bool) {
+	e, ok := obis.Lookup(code)
+	if !ok {
+		return Entity{}, false
+	}
+
+	prefix := "smgw_" + slug(device) + "_"
+	res := Entity{
+		OBIS:              code,
+		UniqueID:          prefix + slug(code),
+		ObjectID:          prefix + slug(e.Description),
+		Name:              e.Description,
+		DeviceClass:       deviceClass(code, e.Quantity),
+		StateClass:        StateClassMeasurement,
+		UnitOfMeasurement: e.Unit,
+	}
+	if e.Category == obis.CategoryCumulative {
+		res.StateClass = StateClassTotalIncreasing
+	}
+
+	return res, true
+}
+
+// deviceClass returns the sensor device class of a quantity
+func deviceClass(code string, q obis.Quantity) string {
+	switch q {
+	case obis.QuantityActivePower:
+		return "power"
+	case obis.QuantityActiveEnergy, obis.QuantityHeatEnergy:
+		return "energy"
+	case obis.QuantityApparentPower:
+		return "apparent_power"
+	case obis.QuantityReactivePower:
+		return "reactive_power"
+	case obis.QuantityPowerFactor:
+		return "power_factor"
+	case obis.QuantityCurrent:
+		return "current"
+	case obis.QuantityVoltage:
+		return "voltage"
+	case obis.QuantityFrequency:
+		return "frequency"
+	case obis.QuantityVolume:
+		c, _ := obis.Parse(code)
+		switch c.Medium() {
+		case obis.MediumGas:
+			return "gas"
+		case obis.MediumWater:
+			return "water"
+		}
+		return "volume"
+	}
+
+	// reactive energy is left without device class for older Home Assistant
+	// releases, the state class alone enables statistics
+	return ""
+}
+
+// slug lowercases s and replaces runs of other characters than letters and
+// digits with underscores, as Home Assistant does for entity IDs
+func slug(s string) string {
+	var b strings.Builder
+	underscore := false
+
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			underscore = false
+			continue
+		}
+		if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "_")
+}
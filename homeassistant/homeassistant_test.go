@@ -0,0 +1,35 @@
+package homeassistant
+
+import "testing"
+
+// TestNewEntity tests device classes, state classes and IDs per register
+func TestNewEntity(t *testing.T) {
+	tests := []struct {
+		code                  string
+		objectID, deviceClass string
+		stateClass, unit      string
+	}{
+		{"1.8.0", "smgw_1emh0012345678_total_energy_import", "energy", StateClassTotalIncreasing, "kWh"},
+		{"2.8.3", "smgw_1emh0012345678_energy_export_tariff_3", "energy", StateClassTotalIncreasing, "kWh"},
+		{"16.7.0", "smgw_1emh0012345678_current_power_active", "power", StateClassMeasurement, "W"},
+		{"31.7.0", "smgw_1emh0012345678_phase_1_current", "current", StateClassMeasurement, "A"},
+		{"13.7.0", "smgw_1emh0012345678_power_factor", "power_factor", StateClassMeasurement, ""},
+		{"3.8.0", "smgw_1emh0012345678_reactive_energy_import", "", StateClassTotalIncreasing, "kvarh"},
+		{"7-0:3.0.0", "smgw_1emh0012345678_gas_volume", "gas", StateClassTotalIncreasing, "m³"},
+	}
+
+	for _, tt := range tests {
+		e, ok := NewEntity("1EMH0012345678", tt.code)
+		if !ok || e.OBIS != tt.code || e.ObjectID != tt.objectID || e.DeviceClass != tt.deviceClass ||
+			e.StateClass != tt.stateClass || e.UnitOfMeasurement != tt.unit {
+			t.Errorf("NewEntity(%s) = %+v, %v", tt.code, e, ok)
+		}
+	}
+
+	if e, _ := NewEntity("1EMH0012345678", "7-0:3.0.0"); e.UniqueID != "smgw_1emh0012345678_7_0_3_0_0" {
+		t.Errorf("UniqueID = %s", e.UniqueID)
+	}
+	if _, ok := NewEntity("home", "96.1.0"); ok {
+		t.Error("NewEntity() of unknown code succeeded")
+	}
+}
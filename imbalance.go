@@ -0,0 +1,43 @@
+package emhcasa
+
+// ImbalanceThresholds are the maximum tolerated differences between phases.
+// Zero disables a check.
+type ImbalanceThresholds struct {
+	CurrentA float64
+	PowerW   float64
+}
+
+// DefaultImbalanceThresholds returns the unbalanced load limit of
+// VDE-AR-N 4100 (4.6 kVA, i.e. 20 A at 230 V).
+func DefaultImbalanceThresholds() ImbalanceThresholds {
+	return ImbalanceThresholds{CurrentA: 20, PowerW: 4600}
+}
+
+// Imbalance is the asymmetry of phases L1-L3, e.g. from a single-phase
+// wallbox or a miswired heat pump.
+type Imbalance struct {
+	CurrentA float64 // difference between highest and lowest phase current (A)
+	PowerW   float64 // difference between highest and lowest phase power (W)
+	Exceeded bool    // a threshold was exceeded
+}
+
+// NewImbalance evaluates the phase imbalance of values returned by GetMeterValues.
+func NewImbalance(values map[string]float64, th ImbalanceThresholds) Imbalance {
+	phases := Phases(values)
+
+	spread := func(v func(PhaseReading) float64) float64 {
+		lo, hi := v(phases[0]), v(phases[0])
+		for _, p := range phases[1:] {
+			lo, hi = min(lo, v(p)), max(hi, v(p))
+		}
+		return hi - lo
+	}
+
+	im := Imbalance{
+		CurrentA: spread(func(p PhaseReading) float64 { return p.CurrentA }),
+		PowerW:   spread(func(p PhaseReading) float64 { return p.PowerW }),
+	}
+	im.Exceeded = th.CurrentA > 0 && im.CurrentA > th.CurrentA || th.PowerW > 0 && im.PowerW > th.PowerW
+
+	return im
+}
@@ -0,0 +1,39 @@
+package emhcasa
+
+import "testing"
+
+// TestNewImbalance tests phase imbalance evaluation against thresholds
+func TestNewImbalance(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]float64
+		th     ImbalanceThresholds
+		want   Imbalance
+	}{
+		{
+			name:   "balanced",
+			values: map[string]float64{"31.7.0": 5, "51.7.0": 6, "71.7.0": 5, "36.7.0": 1150, "56.7.0": 1380, "76.7.0": 1150},
+			th:     DefaultImbalanceThresholds(),
+			want:   Imbalance{CurrentA: 1, PowerW: 230},
+		},
+		{
+			name:   "single-phase wallbox",
+			values: map[string]float64{"31.7.0": 32, "51.7.0": 1, "71.7.0": 1, "36.7.0": 7360, "56.7.0": 230, "76.7.0": 230},
+			th:     DefaultImbalanceThresholds(),
+			want:   Imbalance{CurrentA: 31, PowerW: 7130, Exceeded: true},
+		},
+		{
+			name:   "checks disabled",
+			values: map[string]float64{"31.7.0": 32, "51.7.0": 1, "71.7.0": 1},
+			want:   Imbalance{CurrentA: 31},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewImbalance(tt.values, tt.th); got != tt.want {
+				t.Errorf("NewImbalance() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Package protowire encodes and decodes the protobuf wire format for the
+// hand-written messages of this module, avoiding a protobuf dependency.
+package protowire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Type is a protobuf wire type.
+type Type int
+
+// Wire types
+const (
+	Varint  Type = 0
+	Fixed64 Type = 1
+	Bytes   Type = 2
+	Fixed32 Type = 5
+)
+
+// ErrTruncated is returned for messages ending within a field.
+var ErrTruncated = errors.New("protowire: truncated message")
+
+// AppendTag appends a field tag.
+func AppendTag(b []byte, num int, typ Type) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(typ))
+}
+
+// AppendVarint appends a varint field.
+func AppendVarint(b []byte, num int, v uint64) []byte {
+	b = AppendTag(b, num, Varint)
+	return binary.AppendUvarint(b, v)
+}
+
+// AppendSint appends a zigzag encoded sint64 or sint32 field.
+func AppendSint(b []byte, num int, v int64) []byte {
+	return AppendVarint(b, num, uint64(v<<1^v>>63))
+}
+
+// AppendDouble appends a double field.
+func AppendDouble(b []byte, num int, v float64) []byte {
+	b = AppendTag(b, num, Fixed64)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
+
+// AppendBytes appends a length-delimited field, e.g. bytes, a string or an
+// embedded message.
+func AppendBytes(b []byte, num int, data []byte) []byte {
+	b = AppendTag(b, num, Bytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// AppendString appends a string field.
+func AppendString(b []byte, num int, s string) []byte {
+	b = AppendTag(b, num, Bytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// Field is a decoded field. Varint and fixed values are stored in Uint,
+// length-delimited values in Bytes.
+type Field struct {
+	Num   int
+	Type  Type
+	Uint  uint64
+	Bytes []byte
+}
+
+// Is reports whether f has the field number num and wire type typ. Fields
+// with unexpected wire types are skipped like unknown fields.
+func (f Field) Is(num int, typ Type) bool {
+	return f.Num == num && f.Type == typ
+}
+
+// Int returns a varint field as int64.
+func (f Field) Int() int64 {
+	return int64(f.Uint)
+}
+
+// Sint returns a zigzag encoded field as int64.
+func (f Field) Sint() int64 {
+	return int64(f.Uint>>1) ^ -int64(f.Uint&1)
+}
+
+// Double returns a fixed64 field as float64.
+func (f Field) Double() float64 {
+	return math.Float64frombits(f.Uint)
+}
+
+// Range calls fn for each field of the message b, stopping at the first error.
+func Range(b []byte, fn func(Field) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrTruncated
+		}
+		b = b[n:]
+
+		f := Field{Num: int(tag >> 3), Type: Type(tag & 7)}
+		if f.Num == 0 {
+			return errors.New("protowire: invalid field number 0")
+		}
+
+		switch f.Type {
+		case Varint:
+			if f.Uint, n = binary.Uvarint(b); n <= 0 {
+				return ErrTruncated
+			}
+			b = b[n:]
+
+		case Fixed64:
+			if len(b) < 8 {
+				return ErrTruncated
+			}
+			f.Uint, b = binary.LittleEndian.Uint64(b), b[8:]
+
+		case Fixed32:
+			if len(b) < 4 {
+				return ErrTruncated
+			}
+			f.Uint, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+
+		case Bytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return ErrTruncated
+			}
+			f.Bytes, b = b[n:n+int(size)], b[n+int(size):]
+
+		default:
+			return fmt.Errorf("protowire: unsupported wire type %d", f.Type)
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
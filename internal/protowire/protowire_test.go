@@ -0,0 +1,38 @@
+package protowire
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestRange tests decoding of all wire types and malformed messages
+func TestRange(t *testing.T) {
+	var b []byte
+	b = AppendVarint(b, 1, 300)
+	b = AppendSint(b, 2, -3)
+	b = AppendDouble(b, 3, 1.5)
+	b = AppendString(b, 4, "abc")
+	b = append(b, 5<<3|byte(Fixed32), 1, 0, 0, 0)
+
+	var fields []Field
+	if err := Range(b, func(f Field) error {
+		fields = append(fields, f)
+		return nil
+	}); err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+
+	if len(fields) != 5 || !fields[0].Is(1, Varint) || fields[0].Int() != 300 || fields[1].Sint() != -3 ||
+		fields[2].Double() != 1.5 || string(fields[3].Bytes) != "abc" || fields[4].Uint != 1 {
+		t.Errorf("Range() = %+v", fields)
+	}
+
+	for _, b := range [][]byte{{0x08}, {0x19, 0}, {0x22, 5, 'a'}, {0x80}} {
+		if err := Range(b, func(Field) error { return nil }); !errors.Is(err, ErrTruncated) {
+			t.Errorf("Range(% x) error = %v, want ErrTruncated", b, err)
+		}
+	}
+	if err := Range([]byte{0x0b}, func(Field) error { return nil }); err == nil {
+		t.Error("Range() with group wire type succeeded")
+	}
+}
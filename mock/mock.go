@@ -0,0 +1,190 @@
+// Package mock provides a scriptable emhcasa.Gateway, so applications can
+// test their integration without a physical smart meter gateway.
+//
+// Each call returns the next scripted Response: readings, an error or both,
+// after an optional latency. Once the script is exhausted, the last response
+// is repeated:
+//
+//	gw := mock.New(
+//		mock.Response{Readings: mock.Readings(map[string]float64{obis.Power: 500})},
+//		mock.Response{Err: &emhcasa.StatusError{Code: 503}, Latency: 2 * time.Second},
+//	)
+package mock
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// ErrNoResponse is returned by a Gateway without scripted responses.
+var ErrNoResponse = errors.New("mock: no response scripted")
+
+// Response is the scripted result of a call.
+type Response struct {
+	Readings []emhcasa.Reading
+	Err      error
+	Latency  time.Duration // delay before the call returns
+}
+
+// Gateway returns scripted responses. It is safe for concurrent use.
+type Gateway struct {
+	mu        sync.Mutex
+	responses []Response
+	pos       int // index of the next response
+	calls     int
+	meterID   string
+}
+
+var _ emhcasa.Gateway = (*Gateway)(nil)
+
+// New creates a Gateway returning the responses in order.
+func New(responses ...Response) *Gateway {
+	return &Gateway{responses: responses, meterID: "1EMH0012345678"}
+}
+
+// Add appends responses to the script. If the script was exhausted, the next
+// call returns the first added response.
+func (g *Gateway) Add(responses ...Response) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.pos = min(g.pos, len(g.responses))
+	g.responses = append(g.responses, responses...)
+}
+
+// SetMeterID sets the meter ID returned by MeterID, "1EMH0012345678" by default.
+func (g *Gateway) SetMeterID(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.meterID = id
+}
+
+// MeterID returns the meter ID like emhcasa.Client.
+func (g *Gateway) MeterID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.meterID, nil
+}
+
+// Calls returns the number of calls so far.
+func (g *Gateway) Calls() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.calls
+}
+
+// GetReadings returns the next scripted response.
+func (g *Gateway) GetReadings() ([]emhcasa.Reading, error) {
+	return g.GetReadingsContext(context.Background())
+}
+
+// GetReadingsContext is like GetReadings, returning ctx.Err() if ctx is done
+// before the latency has passed. Readings without timestamp are stamped with
+// the time of the call, like readings without capture time by emhcasa.Client.
+func (g *Gateway) GetReadingsContext(ctx context.Context) ([]emhcasa.Reading, error) {
+	resp, err := g.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Latency > 0 {
+		timer := time.NewTimer(resp.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if resp.Readings == nil {
+		return nil, resp.Err
+	}
+
+	now := time.Now()
+	readings := slices.Clone(resp.Readings)
+	for i := range readings {
+		if readings[i].ReceivedAt.IsZero() {
+			readings[i].ReceivedAt = now
+		}
+		if readings[i].Timestamp.IsZero() {
+			readings[i].Timestamp = readings[i].ReceivedAt
+		}
+	}
+
+	return readings, resp.Err
+}
+
+// next returns the response of the next call
+func (g *Gateway) next() (Response, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.responses) == 0 {
+		return Response{}, ErrNoResponse
+	}
+
+	resp := g.responses[min(g.pos, len(g.responses)-1)]
+	g.pos++
+	g.calls++
+
+	return resp, nil
+}
+
+// Readings creates readings of good quality from values keyed by OBIS code,
+// e.g. obis.Power or "1.8.0", in code order. Units are taken from the obis
+// registry; energies are given in kWh like emhcasa.Reading.Value.
+func Readings(values map[string]float64) []emhcasa.Reading {
+	var res []emhcasa.Reading
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		code, err := obis.Parse(key)
+		if err != nil {
+			continue
+		}
+
+		v := values[key]
+		r := emhcasa.Reading{
+			OBIS:        code.Key(),
+			LogicalName: code.Hex() + ".255",
+			Value:       v,
+			ValueMilli:  int64(math.Round(v * 1000)),
+			Unit:        unit(key),
+			RawValue:    strconv.FormatFloat(v, 'f', -1, 64),
+		}
+
+		// gateways report energies in Wh
+		if strings.HasPrefix(r.ValueUnit(), "k") {
+			r.RawValue = strconv.FormatFloat(v*1000, 'f', -1, 64)
+		}
+
+		res = append(res, r)
+	}
+
+	return res
+}
+
+// unit returns the unit of a code from the obis registry
+func unit(code string) emhcasa.Unit {
+	e, _ := obis.Lookup(code)
+
+	var u emhcasa.Unit
+	if err := u.UnmarshalJSON([]byte(strconv.Quote(strings.TrimPrefix(e.Unit, "k")))); err != nil {
+		return emhcasa.UnitDimensionless
+	}
+	return u
+}
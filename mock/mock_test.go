@@ -0,0 +1,92 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// TestGateway tests scripted responses, repetition of the last response and appending
+func TestGateway(t *testing.T) {
+	errBusy := &emhcasa.StatusError{Code: 503}
+	readings := Readings(map[string]float64{obis.Power: 500})
+
+	gw := New(Response{Readings: readings}, Response{Err: errBusy})
+
+	got, err := gw.GetReadings()
+	if err != nil || len(got) != 1 || got[0].Value != 500 {
+		t.Fatalf("GetReadings() = %+v, %v, want power 500", got, err)
+	}
+	if got[0].Timestamp.IsZero() || got[0].ReceivedAt.IsZero() {
+		t.Errorf("GetReadings() = %+v, want timestamps", got[0])
+	}
+	if !readings[0].Timestamp.IsZero() {
+		t.Error("GetReadings() modified the scripted readings")
+	}
+
+	for range 2 {
+		if _, err := gw.GetReadings(); !errors.Is(err, errBusy) {
+			t.Errorf("GetReadings() error = %v, want %v", err, errBusy)
+		}
+	}
+
+	gw.Add(Response{Readings: readings})
+	if _, err := gw.GetReadings(); err != nil {
+		t.Errorf("GetReadings() after Add() error = %v", err)
+	}
+
+	if n := gw.Calls(); n != 4 {
+		t.Errorf("Calls() = %d, want 4", n)
+	}
+
+	if _, err := New().GetReadings(); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("GetReadings() without script error = %v, want %v", err, ErrNoResponse)
+	}
+}
+
+// TestGatewayLatency tests that latencies are aborted by the context
+func TestGatewayLatency(t *testing.T) {
+	gw := New(Response{Readings: Readings(map[string]float64{obis.Power: 1}), Latency: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := gw.GetReadingsContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetReadingsContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	gw = New(Response{Latency: 20 * time.Millisecond})
+	start := time.Now()
+	if _, err := gw.GetReadings(); err != nil || time.Since(start) < 20*time.Millisecond {
+		t.Errorf("GetReadings() returned after %v, %v, want 20ms", time.Since(start), err)
+	}
+}
+
+// TestReadings tests units and raw values of generated readings
+func TestReadings(t *testing.T) {
+	got := Readings(map[string]float64{obis.Power: 500, obis.EnergyImport: 1234.5})
+
+	if len(got) != 2 {
+		t.Fatalf("Readings() = %+v, want 2 readings", got)
+	}
+
+	energy, power := got[0], got[1]
+	if energy.OBIS != "1.8.0" || energy.Unit != emhcasa.UnitWattHour || energy.RawValue != "1234500" || energy.ValueMilli != 1234500 {
+		t.Errorf("Readings() energy = %+v", energy)
+	}
+	if energy.LogicalName != "0100010800FF.255" {
+		t.Errorf("Readings() logical name = %q, want 0100010800FF.255", energy.LogicalName)
+	}
+	if power.OBIS != "16.7.0" || power.Unit != emhcasa.UnitWatt || power.RawValue != "500" {
+		t.Errorf("Readings() power = %+v", power)
+	}
+
+	id, err := New().MeterID()
+	if err != nil || id == "" {
+		t.Errorf("MeterID() = %q, %v", id, err)
+	}
+}
@@ -0,0 +1,62 @@
+package emhcasa
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// CounterReset describes a decrease of a cumulative register.
+type CounterReset struct {
+	OBIS     string
+	Previous Reading
+	Current  Reading
+}
+
+// Monotonic wraps a Gateway and validates that cumulative registers (energy
+// counters) never decrease. A decreasing reading, e.g. after a counter reset
+// or meter swap, is flagged with QualityCounterReset instead of producing
+// negative consumption downstream.
+type Monotonic struct {
+	gw      Gateway
+	onReset func(CounterReset)
+
+	mu   sync.Mutex
+	last map[string]Reading // last reading per cumulative register
+}
+
+// NewMonotonic wraps gw. onReset is called for every detected reset and may be nil.
+func NewMonotonic(gw Gateway, onReset func(CounterReset)) *Monotonic {
+	return &Monotonic{gw: gw, onReset: onReset, last: make(map[string]Reading)}
+}
+
+// GetReadings implements Gateway.
+func (m *Monotonic) GetReadings() ([]Reading, error) {
+	readings, err := m.gw.GetReadings()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	readings = slices.Clone(readings) // may be shared by the wrapped gateway
+	for i, r := range readings {
+		if e, ok := obis.Lookup(r.OBIS); !ok || e.Category != obis.CategoryCumulative {
+			continue
+		}
+
+		if prev, ok := m.last[r.OBIS]; ok && r.Value < prev.Value {
+			readings[i].Quality = max(r.Quality, QualityCounterReset)
+			if m.onReset != nil {
+				m.onReset(CounterReset{OBIS: r.OBIS, Previous: prev, Current: r})
+			}
+		}
+
+		// follow the new counter after a reset
+		m.last[r.OBIS] = r
+	}
+
+	return readings, nil
+}
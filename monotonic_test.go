@@ -0,0 +1,44 @@
+package emhcasa
+
+import "testing"
+
+// TestMonotonic tests flagging of decreasing energy counters
+func TestMonotonic(t *testing.T) {
+	values := [][2]float64{{100, 230}, {101, 231}, {5, 229}, {6, 230}} // 1.8.0, 32.7.0
+	polls := make([][]Reading, len(values))
+	for i, v := range values {
+		polls[i] = []Reading{{OBIS: "1.8.0", Value: v[0]}, {OBIS: "32.7.0", Value: v[1]}}
+	}
+
+	var poll int
+	gw := gatewayFunc(func() ([]Reading, error) {
+		r := polls[poll] // shared like a cache, must not be modified
+		poll++
+		return r, nil
+	})
+
+	var resets []CounterReset
+	m := NewMonotonic(gw, func(r CounterReset) { resets = append(resets, r) })
+
+	wantQuality := []Quality{QualityGood, QualityGood, QualityCounterReset, QualityGood}
+	for i, want := range wantQuality {
+		readings, err := m.GetReadings()
+		if err != nil {
+			t.Fatalf("GetReadings() error = %v", err)
+		}
+		if readings[0].Quality != want {
+			t.Errorf("poll %d: 1.8.0 quality = %v, want %v", i, readings[0].Quality, want)
+		}
+		if readings[1].Quality != QualityGood {
+			t.Errorf("poll %d: 32.7.0 quality = %v, want good", i, readings[1].Quality)
+		}
+	}
+
+	if q := polls[2][0].Quality; q != QualityGood {
+		t.Errorf("readings of the wrapped gateway modified: quality = %v", q)
+	}
+
+	if len(resets) != 1 || resets[0].OBIS != "1.8.0" || resets[0].Previous.Value != 101 || resets[0].Current.Value != 5 {
+		t.Errorf("resets = %+v, want one 1.8.0 reset from 101 to 5", resets)
+	}
+}
@@ -0,0 +1,50 @@
+package obis
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether a reduced C.D.E code matches pattern. Each value group
+// of the pattern is matched separately using path.Match syntax, so "*" matches
+// a whole group and "?" a single digit, e.g. "*.8.*" for all energy registers
+// or "3?.7.0" for 30.7.0-39.7.0, among them the phase 1 current 31.7.0 and
+// voltage 32.7.0. Malformed patterns match nothing.
+func Match(pattern, code string) bool {
+	pg, cg := strings.Split(pattern, "."), strings.Split(code, ".")
+	if len(pg) != len(cg) {
+		return false
+	}
+
+	for i := range pg {
+		if ok, err := path.Match(pg[i], cg[i]); !ok || err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filter selects readings by a list of Match patterns.
+type Filter []string
+
+// Match reports whether code matches any pattern of the filter.
+func (f Filter) Match(code string) bool {
+	for _, p := range f {
+		if Match(p, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the values whose codes match the filter.
+func (f Filter) Apply(values map[string]float64) map[string]float64 {
+	res := make(map[string]float64)
+	for code, v := range values {
+		if f.Match(code) {
+			res[code] = v
+		}
+	}
+	return res
+}
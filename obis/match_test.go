@@ -0,0 +1,49 @@
+package obis
+
+import (
+	"maps"
+	"testing"
+)
+
+// TestMatch tests wildcard matching of reduced codes
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		code    string
+		want    bool
+	}{
+		{"1.8.0", "1.8.0", true},
+		{"*.8.*", "1.8.0", true},
+		{"*.8.*", "2.8.1", true},
+		{"*.8.*", "16.7.0", false},
+		{"*.8.*", "18.7.0", false},
+		{"3?.7.0", "31.7.0", true},
+		{"3?.7.0", "39.7.0", true},
+		{"3?.7.0", "3.7.0", false},
+		{"3?.7.0", "41.7.0", false},
+		{"*", "1.8.0", false},
+		{"[.8.0", "1.8.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.code, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.code); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFilterApply tests selecting values by multiple patterns
+func TestFilterApply(t *testing.T) {
+	values := map[string]float64{
+		"1.8.0": 100, "2.8.0": 20, "16.7.0": 500,
+		"32.7.0": 230, "52.7.0": 231, "72.7.0": 229, "31.7.0": 2, "12.7.0": 230,
+	}
+
+	got := Filter{"*.8.*", "[357]2.7.0"}.Apply(values)
+	want := map[string]float64{"1.8.0": 100, "2.8.0": 20, "32.7.0": 230, "52.7.0": 231, "72.7.0": 229}
+	if !maps.Equal(got, want) {
+		t.Errorf("Apply() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+// Package obis implements OBIS codes (IEC 62056-61) identifying meter registers.
+//
+// A full OBIS code has six value groups A-B:C.D.E*F, e.g. "1-0:1.8.0*255" for
+// the total imported active energy of an electricity meter. Most consumers only
+// care about the reduced C.D.E form ("1.8.0"), which the emhcasa client uses as
+// map keys.
+package obis
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// reCode matches [A-B:]C.D.E[*F]
+var reCode = regexp.MustCompile(`^(?:([0-9]{1,3})-([0-9]{1,3}):)?([0-9]{1,3})\.([0-9]{1,3})\.([0-9]{1,3})(?:\*([0-9]{1,3}))?$`)
+
+// Medium is the energy type given by the A value group of a code.
+type Medium uint8
+
+const (
+	MediumAbstract    Medium = 0
+	MediumElectricity Medium = 1
+	MediumHeat        Medium = 6
+	MediumGas         Medium = 7
+	MediumWater       Medium = 8
+)
+
+// String returns the medium name.
+func (m Medium) String() string {
+	switch m {
+	case MediumAbstract:
+		return "abstract"
+	case MediumElectricity:
+		return "electricity"
+	case MediumHeat:
+		return "heat"
+	case MediumGas:
+		return "gas"
+	case MediumWater:
+		return "water"
+	}
+	return fmt.Sprintf("Medium(%d)", uint8(m))
+}
+
+// Code is an OBIS code with the value groups A-B:C.D.E*F.
+type Code struct {
+	A uint8 // medium, 1 = electricity
+	B uint8 // channel
+	C uint8 // physical quantity, e.g. 1 = active power import
+	D uint8 // measurement type, e.g. 7 = instantaneous, 8 = time integral
+	E uint8 // tariff or further classification
+	F uint8 // historical value, 255 = current
+}
+
+// Parse parses an OBIS code in full (A-B:C.D.E*F), partial (A-B:C.D.E) or
+// reduced (C.D.E) notation. Missing value groups default to an electricity
+// meter's current value: A=1, B=0, F=255.
+func Parse(s string) (Code, error) {
+	m := reCode.FindStringSubmatch(s)
+	if m == nil {
+		return Code{}, fmt.Errorf("invalid OBIS code: %s", s)
+	}
+
+	defaults := [6]string{"1", "0", "", "", "", "255"}
+
+	var groups [6]uint8
+	for i, g := range m[1:] {
+		if g == "" {
+			g = defaults[i]
+		}
+		v, err := strconv.ParseUint(g, 10, 8)
+		if err != nil {
+			return Code{}, fmt.Errorf("invalid OBIS code: %s", s)
+		}
+		groups[i] = uint8(v)
+	}
+
+	return Code{A: groups[0], B: groups[1], C: groups[2], D: groups[3], E: groups[4], F: groups[5]}, nil
+}
+
+// ParseHex parses the 12 character hex representation used by CASA gateways
+// as logical name, e.g. "0100010800FF" for 1-0:1.8.0*255. Names registered
+// with RegisterHex take precedence.
+func ParseHex(s string) (Code, error) {
+	if c, ok := lookupHex(s); ok {
+		return c, nil
+	}
+
+	if len(s) != 12 {
+		return Code{}, fmt.Errorf("unexpected logical name: %s", s)
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return Code{}, fmt.Errorf("unexpected logical name: %s", s)
+	}
+
+	return Code{A: b[0], B: b[1], C: b[2], D: b[3], E: b[4], F: b[5]}, nil
+}
+
+// String returns the full notation A-B:C.D.E*F.
+func (c Code) String() string {
+	return fmt.Sprintf("%d-%d:%d.%d.%d*%d", c.A, c.B, c.C, c.D, c.E, c.F)
+}
+
+// Short returns the reduced notation C.D.E.
+func (c Code) Short() string {
+	return fmt.Sprintf("%d.%d.%d", c.C, c.D, c.E)
+}
+
+// Medium returns the medium given by the A value group.
+func (c Code) Medium() Medium {
+	return Medium(c.A)
+}
+
+// Key returns the reduced notation C.D.E for electricity and the partial
+// notation A-B:C.D.E for other media, so gas, water or heat registers don't
+// collide with electricity registers of the same C.D.E.
+func (c Code) Key() string {
+	if c.Medium() == MediumElectricity {
+		return c.Short()
+	}
+	return fmt.Sprintf("%d-%d:%s", c.A, c.B, c.Short())
+}
+
+// Hex returns the 12 character hex representation used by CASA gateways as
+// logical name, e.g. "0100010800FF" for 1-0:1.8.0*255.
+func (c Code) Hex() string {
+	return fmt.Sprintf("%02X%02X%02X%02X%02X%02X", c.A, c.B, c.C, c.D, c.E, c.F)
+}
+
+// Equal reports whether both codes are identical in all value groups.
+func (c Code) Equal(o Code) bool {
+	return c == o
+}
+
+// EqualShort reports whether both codes identify the same register, ignoring
+// medium (A), channel (B) and historical value (F).
+func (c Code) EqualShort(o Code) bool {
+	return c.C == o.C && c.D == o.D && c.E == o.E
+}
@@ -0,0 +1,156 @@
+package obis
+
+import "testing"
+
+// TestParse tests parsing of the supported OBIS notations
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Code
+		wantErr bool
+	}{
+		{
+			name:  "full notation",
+			input: "1-0:1.8.0*255",
+			want:  Code{A: 1, B: 0, C: 1, D: 8, E: 0, F: 255},
+		},
+		{
+			name:  "partial notation",
+			input: "7-0:3.0.0",
+			want:  Code{A: 7, B: 0, C: 3, D: 0, E: 0, F: 255},
+		},
+		{
+			name:  "reduced notation",
+			input: "16.7.0",
+			want:  Code{A: 1, B: 0, C: 16, D: 7, E: 0, F: 255},
+		},
+		{
+			name:  "historical value",
+			input: "1.8.1*12",
+			want:  Code{A: 1, B: 0, C: 1, D: 8, E: 1, F: 12},
+		},
+		{
+			name:    "missing group",
+			input:   "1.8",
+			wantErr: true,
+		},
+		{
+			name:    "value out of range",
+			input:   "1.8.256",
+			wantErr: true,
+		},
+		{
+			name:    "invalid characters",
+			input:   "1-x:1.8.0",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseHex tests parsing of CASA hex logical names
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Code
+		wantErr bool
+	}{
+		{
+			name:  "current power",
+			input: "0100100700FF",
+			want:  Code{A: 1, B: 0, C: 16, D: 7, E: 0, F: 255},
+		},
+		{
+			name:  "phase 1 current",
+			input: "01001F0700FF",
+			want:  Code{A: 1, B: 0, C: 31, D: 7, E: 0, F: 255},
+		},
+		{
+			name:    "invalid length",
+			input:   "010010",
+			wantErr: true,
+		},
+		{
+			name:    "invalid hex",
+			input:   "0100ZZZZ00FF",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHex(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCodeFormatting tests String, Short and equality helpers
+func TestCodeFormatting(t *testing.T) {
+	c := Code{A: 1, B: 0, C: 2, D: 8, E: 0, F: 255}
+
+	if got := c.String(); got != "1-0:2.8.0*255" {
+		t.Errorf("String() = %v, want 1-0:2.8.0*255", got)
+	}
+	if got := c.Short(); got != "2.8.0" {
+		t.Errorf("Short() = %v, want 2.8.0", got)
+	}
+	if got := c.Hex(); got != "0100020800FF" {
+		t.Errorf("Hex() = %v, want 0100020800FF", got)
+	}
+
+	other := Code{A: 1, B: 1, C: 2, D: 8, E: 0, F: 255}
+	if c.Equal(other) {
+		t.Errorf("Equal() = true for different channels")
+	}
+	if !c.EqualShort(other) {
+		t.Errorf("EqualShort() = false for same register")
+	}
+}
+
+// TestCodeMedium tests medium detection and medium-aware keys
+func TestCodeMedium(t *testing.T) {
+	tests := []struct {
+		input   string
+		medium  string
+		wantKey string
+	}{
+		{"1-0:1.8.0*255", "electricity", "1.8.0"},
+		{"7-0:3.0.0*255", "gas", "7-0:3.0.0"},
+		{"8-0:1.0.0", "water", "8-0:1.0.0"},
+		{"6-0:1.0.0", "heat", "6-0:1.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := c.Medium().String(); got != tt.medium {
+				t.Errorf("Medium() = %v, want %v", got, tt.medium)
+			}
+			if got := c.Key(); got != tt.wantKey {
+				t.Errorf("Key() = %v, want %v", got, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package obis
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Known registers in the notation used as keys by GetMeterValues (see Code.Key).
+const (
+	PowerImport   = "1.7.0"
+	PowerExport   = "2.7.0"
+	Power         = "16.7.0" // signed, positive = import
+	PowerImportL1 = "21.7.0"
+	PowerExportL1 = "22.7.0"
+	PowerImportL2 = "41.7.0"
+	PowerExportL2 = "42.7.0"
+	PowerImportL3 = "61.7.0"
+	PowerExportL3 = "62.7.0"
+	PowerL1       = "36.7.0"
+	PowerL2       = "56.7.0"
+	PowerL3       = "76.7.0"
+
+	EnergyImport        = "1.8.0"
+	EnergyImportTariff1 = "1.8.1" // HT
+	EnergyImportTariff2 = "1.8.2" // NT
+	EnergyExport        = "2.8.0"
+	EnergyExportTariff1 = "2.8.1" // HT
+	EnergyExportTariff2 = "2.8.2" // NT
+
+	ReactivePowerImport  = "3.7.0"
+	ReactivePowerExport  = "4.7.0"
+	ReactiveEnergyImport = "3.8.0"
+	ReactiveEnergyExport = "4.8.0"
+
+	ApparentPower   = "9.7.0"
+	ApparentPowerL1 = "29.7.0"
+	ApparentPowerL2 = "49.7.0"
+	ApparentPowerL3 = "69.7.0"
+	PowerFactor     = "13.7.0"
+	PowerFactorL1   = "33.7.0"
+	PowerFactorL2   = "53.7.0"
+	PowerFactorL3   = "73.7.0"
+
+	CurrentL1 = "31.7.0"
+	CurrentL2 = "51.7.0"
+	CurrentL3 = "71.7.0"
+	VoltageL1 = "32.7.0"
+	VoltageL2 = "52.7.0"
+	VoltageL3 = "72.7.0"
+	Frequency = "14.7.0"
+
+	HeatEnergy  = "6-0:1.0.0"
+	GasVolume   = "7-0:3.0.0"
+	WaterVolume = "8-0:1.0.0"
+)
+
+// Quantity is the physical quantity measured by a register.
+type Quantity string
+
+const (
+	QuantityActivePower    Quantity = "active power"
+	QuantityActiveEnergy   Quantity = "active energy"
+	QuantityApparentPower  Quantity = "apparent power"
+	QuantityReactivePower  Quantity = "reactive power"
+	QuantityReactiveEnergy Quantity = "reactive energy"
+	QuantityPowerFactor    Quantity = "power factor"
+	QuantityCurrent        Quantity = "current"
+	QuantityVoltage        Quantity = "voltage"
+	QuantityFrequency      Quantity = "frequency"
+	QuantityVolume         Quantity = "volume"
+	QuantityHeatEnergy     Quantity = "thermal energy"
+)
+
+// Direction is the energy flow direction of a register.
+type Direction string
+
+const (
+	DirectionNone   Direction = ""       // not directional (current, voltage, frequency)
+	DirectionImport Direction = "import" // drawn from the grid
+	DirectionExport Direction = "export" // fed into the grid
+	DirectionNet    Direction = "net"    // signed, positive = import
+)
+
+// Category distinguishes instantaneous values from cumulative counters.
+type Category string
+
+const (
+	CategoryInstantaneous Category = "instantaneous" // D = 7, e.g. power
+	CategoryCumulative    Category = "cumulative"    // D = 8, e.g. energy counters
+)
+
+// Entry describes a known register.
+type Entry struct {
+	Description string
+	Unit        string // unit of the value as returned by emhcasa, e.g. "kWh"
+	Quantity    Quantity
+	Direction   Direction
+	Phase       int // 1-3 for phase values, 0 for totals
+	Category    Category
+}
+
+// mu guards registry and hexNames against concurrent registration
+var mu sync.RWMutex
+
+// hexNames maps vendor-specific CASA logical names to codes
+var hexNames = map[string]Code{}
+
+// registry maps code keys (see Code.Key) to their metadata
+var registry = map[string]Entry{
+	PowerImport:   {"Active Power Import", "W", QuantityActivePower, DirectionImport, 0, CategoryInstantaneous},
+	PowerExport:   {"Active Power Export", "W", QuantityActivePower, DirectionExport, 0, CategoryInstantaneous},
+	Power:         {"Current Power (Active)", "W", QuantityActivePower, DirectionNet, 0, CategoryInstantaneous},
+	PowerImportL1: {"Phase 1 Power Import", "W", QuantityActivePower, DirectionImport, 1, CategoryInstantaneous},
+	PowerExportL1: {"Phase 1 Power Export", "W", QuantityActivePower, DirectionExport, 1, CategoryInstantaneous},
+	PowerImportL2: {"Phase 2 Power Import", "W", QuantityActivePower, DirectionImport, 2, CategoryInstantaneous},
+	PowerExportL2: {"Phase 2 Power Export", "W", QuantityActivePower, DirectionExport, 2, CategoryInstantaneous},
+	PowerImportL3: {"Phase 3 Power Import", "W", QuantityActivePower, DirectionImport, 3, CategoryInstantaneous},
+	PowerExportL3: {"Phase 3 Power Export", "W", QuantityActivePower, DirectionExport, 3, CategoryInstantaneous},
+	PowerL1:       {"Phase 1 Power", "W", QuantityActivePower, DirectionNet, 1, CategoryInstantaneous},
+	PowerL2:       {"Phase 2 Power", "W", QuantityActivePower, DirectionNet, 2, CategoryInstantaneous},
+	PowerL3:       {"Phase 3 Power", "W", QuantityActivePower, DirectionNet, 3, CategoryInstantaneous},
+
+	EnergyImport:        {"Total Energy Import", "kWh", QuantityActiveEnergy, DirectionImport, 0, CategoryCumulative},
+	EnergyImportTariff1: {"Energy Import Tariff 1 (HT)", "kWh", QuantityActiveEnergy, DirectionImport, 0, CategoryCumulative},
+	EnergyImportTariff2: {"Energy Import Tariff 2 (NT)", "kWh", QuantityActiveEnergy, DirectionImport, 0, CategoryCumulative},
+	EnergyExport:        {"Total Energy Export", "kWh", QuantityActiveEnergy, DirectionExport, 0, CategoryCumulative},
+	EnergyExportTariff1: {"Energy Export Tariff 1 (HT)", "kWh", QuantityActiveEnergy, DirectionExport, 0, CategoryCumulative},
+	EnergyExportTariff2: {"Energy Export Tariff 2 (NT)", "kWh", QuantityActiveEnergy, DirectionExport, 0, CategoryCumulative},
+
+	ReactivePowerImport:  {"Reactive Power Import", "var", QuantityReactivePower, DirectionImport, 0, CategoryInstantaneous},
+	ReactivePowerExport:  {"Reactive Power Export", "var", QuantityReactivePower, DirectionExport, 0, CategoryInstantaneous},
+	ReactiveEnergyImport: {"Reactive Energy Import", "kvarh", QuantityReactiveEnergy, DirectionImport, 0, CategoryCumulative},
+	ReactiveEnergyExport: {"Reactive Energy Export", "kvarh", QuantityReactiveEnergy, DirectionExport, 0, CategoryCumulative},
+
+	ApparentPower:   {"Apparent Power Import", "VA", QuantityApparentPower, DirectionImport, 0, CategoryInstantaneous},
+	ApparentPowerL1: {"Phase 1 Apparent Power Import", "VA", QuantityApparentPower, DirectionImport, 1, CategoryInstantaneous},
+	ApparentPowerL2: {"Phase 2 Apparent Power Import", "VA", QuantityApparentPower, DirectionImport, 2, CategoryInstantaneous},
+	ApparentPowerL3: {"Phase 3 Apparent Power Import", "VA", QuantityApparentPower, DirectionImport, 3, CategoryInstantaneous},
+	PowerFactor:     {"Power Factor", "", QuantityPowerFactor, DirectionNone, 0, CategoryInstantaneous},
+	PowerFactorL1:   {"Phase 1 Power Factor", "", QuantityPowerFactor, DirectionNone, 1, CategoryInstantaneous},
+	PowerFactorL2:   {"Phase 2 Power Factor", "", QuantityPowerFactor, DirectionNone, 2, CategoryInstantaneous},
+	PowerFactorL3:   {"Phase 3 Power Factor", "", QuantityPowerFactor, DirectionNone, 3, CategoryInstantaneous},
+
+	CurrentL1: {"Phase 1 Current", "A", QuantityCurrent, DirectionNone, 1, CategoryInstantaneous},
+	CurrentL2: {"Phase 2 Current", "A", QuantityCurrent, DirectionNone, 2, CategoryInstantaneous},
+	CurrentL3: {"Phase 3 Current", "A", QuantityCurrent, DirectionNone, 3, CategoryInstantaneous},
+	VoltageL1: {"Phase 1 Voltage", "V", QuantityVoltage, DirectionNone, 1, CategoryInstantaneous},
+	VoltageL2: {"Phase 2 Voltage", "V", QuantityVoltage, DirectionNone, 2, CategoryInstantaneous},
+	VoltageL3: {"Phase 3 Voltage", "V", QuantityVoltage, DirectionNone, 3, CategoryInstantaneous},
+	Frequency: {"Grid Frequency", "Hz", QuantityFrequency, DirectionNone, 0, CategoryInstantaneous},
+
+	HeatEnergy:  {"Heat Energy", "kWh", QuantityHeatEnergy, DirectionImport, 0, CategoryCumulative},
+	GasVolume:   {"Gas Volume", "m³", QuantityVolume, DirectionImport, 0, CategoryCumulative},
+	WaterVolume: {"Water Volume", "m³", QuantityVolume, DirectionImport, 0, CategoryCumulative},
+}
+
+// Lookup returns the registry entry for a code such as "1.8.0" or "7-0:3.0.0".
+// Energy tariff registers 1.8.E and 2.8.E are known for any tariff E.
+func Lookup(code string) (Entry, bool) {
+	c, err := Parse(code)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if e, ok := registry[c.Key()]; ok {
+		return e, true
+	}
+
+	if c.Medium() != MediumElectricity || (c.C != 1 && c.C != 2) || c.D != 8 || c.E == 0 {
+		return Entry{}, false
+	}
+
+	e, dir := registry[EnergyImport], "Import"
+	if c.C == 2 {
+		e, dir = registry[EnergyExport], "Export"
+	}
+	e.Description = fmt.Sprintf("Energy %s Tariff %d", dir, c.E)
+
+	return e, true
+}
+
+// Description returns a human-readable description for a reduced C.D.E code,
+// or the code itself if it is unknown.
+func Description(code string) string {
+	if e, ok := Lookup(code); ok {
+		return e.Description
+	}
+	return code
+}
+
+// Search returns the keys (see Code.Key) of the registry entries whose key or
+// description contains term, ignoring case, in code order.
+func Search(term string) []string {
+	term = strings.ToLower(term)
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var res []string
+	for key, e := range registry {
+		if strings.Contains(key, term) || strings.Contains(strings.ToLower(e.Description), term) {
+			res = append(res, key)
+		}
+	}
+
+	slices.SortFunc(res, func(a, b string) int {
+		ca, _ := Parse(a)
+		cb, _ := Parse(b)
+		return cmp.Or(
+			cmp.Compare(ca.A, cb.A), cmp.Compare(ca.B, cb.B),
+			cmp.Compare(ca.C, cb.C), cmp.Compare(ca.D, cb.D), cmp.Compare(ca.E, cb.E),
+		)
+	})
+
+	return res
+}
+
+// Register adds or replaces the registry entry for a code, so registers not
+// known to this package are described without waiting for a library release.
+func Register(code string, e Entry) error {
+	c, err := Parse(code)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	registry[c.Key()] = e
+	return nil
+}
+
+// RegisterHex maps a non-standard CASA logical name, e.g. of a vendor firmware
+// variant, to an OBIS code. ParseHex returns the registered code for it instead
+// of decoding the bytes.
+func RegisterHex(name string, code Code) error {
+	if len(name) != 12 {
+		return fmt.Errorf("unexpected logical name: %s", name)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	hexNames[strings.ToUpper(name)] = code
+	return nil
+}
+
+// HexNames returns the logical names registered with RegisterHex for the
+// register of c (see Code.Key), sorted.
+func HexNames(c Code) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var res []string
+	for name, code := range hexNames {
+		if code.Key() == c.Key() {
+			res = append(res, name)
+		}
+	}
+
+	slices.Sort(res)
+	return res
+}
+
+// lookupHex returns the code registered for a CASA logical name
+func lookupHex(name string) (Code, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	c, ok := hexNames[strings.ToUpper(name)]
+	return c, ok
+}
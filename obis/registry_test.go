@@ -0,0 +1,135 @@
+package obis
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestLookup tests registry metadata of common codes
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		code   string
+		want   Entry
+		wantOk bool
+	}{
+		{
+			code:   "1.8.0",
+			want:   Entry{"Total Energy Import", "kWh", QuantityActiveEnergy, DirectionImport, 0, CategoryCumulative},
+			wantOk: true,
+		},
+		{
+			code:   "16.7.0",
+			want:   Entry{"Current Power (Active)", "W", QuantityActivePower, DirectionNet, 0, CategoryInstantaneous},
+			wantOk: true,
+		},
+		{
+			code:   "52.7.0",
+			want:   Entry{"Phase 2 Voltage", "V", QuantityVoltage, DirectionNone, 2, CategoryInstantaneous},
+			wantOk: true,
+		},
+		{
+			code:   EnergyExportTariff1,
+			want:   Entry{"Energy Export Tariff 1 (HT)", "kWh", QuantityActiveEnergy, DirectionExport, 0, CategoryCumulative},
+			wantOk: true,
+		},
+		{
+			code:   "1.8.5",
+			want:   Entry{"Energy Import Tariff 5", "kWh", QuantityActiveEnergy, DirectionImport, 0, CategoryCumulative},
+			wantOk: true,
+		},
+		{
+			code:   GasVolume,
+			want:   Entry{"Gas Volume", "m³", QuantityVolume, DirectionImport, 0, CategoryCumulative},
+			wantOk: true,
+		},
+		{
+			code:   "1-0:3.0.0",
+			wantOk: false,
+		},
+		{
+			code:   "3.8.1",
+			wantOk: false,
+		},
+		{
+			code:   "99.99.99",
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, ok := Lookup(tt.code)
+			if ok != tt.wantOk {
+				t.Fatalf("Lookup() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDescription tests descriptions with fallback to the code
+func TestDescription(t *testing.T) {
+	if got := Description("2.8.0"); got != "Total Energy Export" {
+		t.Errorf("Description(2.8.0) = %v, want Total Energy Export", got)
+	}
+	if got := Description("99.99.99"); got != "99.99.99" {
+		t.Errorf("Description(99.99.99) = %v, want 99.99.99", got)
+	}
+}
+
+// TestRegister tests runtime registration of custom codes and logical names
+func TestRegister(t *testing.T) {
+	t.Cleanup(func() {
+		delete(registry, "96.50.1")
+		delete(hexNames, "0100C8010000")
+	})
+
+	e := Entry{Description: "Vendor Status", Category: CategoryInstantaneous}
+	if err := Register("1-0:96.50.1*255", e); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if got, ok := Lookup("96.50.1"); !ok || got != e {
+		t.Errorf("Lookup() = %+v, %v, want %+v", got, ok, e)
+	}
+	if err := Register("invalid", e); err == nil {
+		t.Error("Register() expected error for invalid code")
+	}
+
+	// vendor variant reporting 1.8.0 under a different name
+	want := Code{A: 1, B: 0, C: 1, D: 8, E: 0, F: 255}
+	if err := RegisterHex("0100c8010000", want); err != nil {
+		t.Fatalf("RegisterHex() error = %v", err)
+	}
+	if got, err := ParseHex("0100C8010000"); err != nil || got != want {
+		t.Errorf("ParseHex() = %v, %v, want %v", got, err, want)
+	}
+	if got := HexNames(Code{A: 1, C: 1, D: 8}); !slices.Equal(got, []string{"0100C8010000"}) {
+		t.Errorf("HexNames() = %v, want [0100C8010000]", got)
+	}
+	if err := RegisterHex("0100", want); err == nil {
+		t.Error("RegisterHex() expected error for invalid length")
+	}
+}
+
+// TestSearch tests searching the registry by code and description
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{"1.8.", []string{EnergyImport, EnergyImportTariff1, EnergyImportTariff2}},
+		{"phase 1 power", []string{PowerImportL1, PowerExportL1, PowerFactorL1, PowerL1}},
+		{"GAS", []string{GasVolume}},
+		{"nothing", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			if got := Search(tt.term); !slices.Equal(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package emhcasa
+
+import (
+	"math"
+	"sync"
+
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// Range is an inclusive range of plausible values.
+type Range struct {
+	Min, Max float64
+}
+
+// PlausibilityRules configures NewPlausible. Rules apply to all registers of
+// a physical quantity as known by obis.Lookup.
+type PlausibilityRules struct {
+	Ranges   map[obis.Quantity]Range   // plausible value ranges
+	MaxDelta map[obis.Quantity]float64 // maximum change between consecutive polls
+	Drop     bool                      // remove implausible readings instead of flagging them
+}
+
+// DefaultPlausibilityRules returns rules for a residential grid connection:
+// voltage 180-260 V, frequency 45-55 Hz and active power within ±50 kW.
+// No delta rules are set as plausible changes depend on the polling interval.
+func DefaultPlausibilityRules() PlausibilityRules {
+	return PlausibilityRules{
+		Ranges: map[obis.Quantity]Range{
+			obis.QuantityVoltage:     {180, 260},
+			obis.QuantityFrequency:   {45, 55},
+			obis.QuantityActivePower: {-50000, 50000},
+		},
+	}
+}
+
+// Plausible wraps a Gateway and downgrades readings violating plausibility
+// rules, e.g. spikes from transmission errors, to QualityInvalid.
+type Plausible struct {
+	gw    Gateway
+	rules PlausibilityRules
+
+	mu   sync.Mutex
+	last map[string]float64 // last plausible value per register with delta rule
+}
+
+// NewPlausible wraps gw applying the given rules.
+func NewPlausible(gw Gateway, rules PlausibilityRules) *Plausible {
+	return &Plausible{gw: gw, rules: rules, last: make(map[string]float64)}
+}
+
+// GetReadings implements Gateway.
+func (p *Plausible) GetReadings() ([]Reading, error) {
+	readings, err := p.gw.GetReadings()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	res := make([]Reading, 0, len(readings))
+	for _, r := range readings {
+		if !p.plausible(r) {
+			if p.rules.Drop {
+				continue
+			}
+			r.Quality = QualityInvalid
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// plausible checks r against the rules of its quantity
+func (p *Plausible) plausible(r Reading) bool {
+	e, ok := obis.Lookup(r.OBIS)
+	if !ok {
+		return true
+	}
+
+	if rg, ok := p.rules.Ranges[e.Quantity]; ok && (r.Value < rg.Min || r.Value > rg.Max) {
+		return false
+	}
+
+	if d, ok := p.rules.MaxDelta[e.Quantity]; ok {
+		if last, ok := p.last[r.OBIS]; ok && math.Abs(r.Value-last) > d {
+			return false
+		}
+		p.last[r.OBIS] = r.Value
+	}
+
+	return true
+}
@@ -0,0 +1,80 @@
+package emhcasa
+
+import (
+	"testing"
+
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// TestPlausible tests range and delta rules
+func TestPlausible(t *testing.T) {
+	polls := [][]Reading{
+		{{OBIS: "32.7.0", Value: 230}, {OBIS: "14.7.0", Value: 50}, {OBIS: "1.8.0", Value: 100}},
+		{{OBIS: "32.7.0", Value: 23000}, {OBIS: "14.7.0", Value: 0}, {OBIS: "1.8.0", Value: 9000}},
+		{{OBIS: "32.7.0", Value: 231}, {OBIS: "14.7.0", Value: 49.9}, {OBIS: "1.8.0", Value: 101}},
+	}
+
+	tests := []struct {
+		name string
+		drop bool
+		want [][]Quality // per poll, nil entries = dropped
+	}{
+		{
+			name: "flag",
+			want: [][]Quality{
+				{QualityGood, QualityGood, QualityGood},
+				{QualityInvalid, QualityInvalid, QualityInvalid},
+				{QualityGood, QualityGood, QualityGood},
+			},
+		},
+		{
+			name: "drop",
+			drop: true,
+			want: [][]Quality{
+				{QualityGood, QualityGood, QualityGood},
+				{},
+				{QualityGood, QualityGood, QualityGood},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var poll int
+			gw := gatewayFunc(func() ([]Reading, error) {
+				r := polls[poll] // shared like a cache, must not be modified
+				poll++
+				return r, nil
+			})
+
+			rules := DefaultPlausibilityRules()
+			rules.MaxDelta = map[obis.Quantity]float64{obis.QuantityActiveEnergy: 10}
+			rules.Drop = tt.drop
+			p := NewPlausible(gw, rules)
+
+			for i, want := range tt.want {
+				readings, err := p.GetReadings()
+				if err != nil {
+					t.Fatalf("GetReadings() error = %v", err)
+				}
+				if len(readings) != len(want) {
+					t.Fatalf("poll %d: %d readings, want %d", i, len(readings), len(want))
+				}
+				for j, q := range want {
+					if readings[j].Quality != q {
+						t.Errorf("poll %d: %s quality = %v, want %v", i, readings[j].OBIS, readings[j].Quality, q)
+					}
+				}
+			}
+
+			for i, poll := range polls {
+				for _, r := range poll {
+					if r.Quality != QualityGood {
+						t.Errorf("poll %d: readings of the wrapped gateway modified: %+v", i, poll)
+						break
+					}
+				}
+			}
+		})
+	}
+}
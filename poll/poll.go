@@ -0,0 +1,94 @@
+// Package poll periodically reads meter values from a gateway.
+package poll
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// Poller reads a gateway at a fixed interval and reports the results via callbacks.
+type Poller struct {
+	Gateway  emhcasa.Gateway
+	Interval time.Duration
+	Jitter   time.Duration // random delay up to Jitter before each poll, spreads load of many pollers
+
+	OnReadings func([]emhcasa.Reading) // called after each successful poll
+	OnError    func(error)             // called after each failed poll
+}
+
+// Result is the outcome of a single poll.
+type Result struct {
+	Time     time.Time // time the poll finished
+	Readings []emhcasa.Reading
+	Err      error
+}
+
+// Run polls until ctx is cancelled and returns ctx.Err().
+// Polls never overlap; callbacks are called from the polling goroutine.
+func (p *Poller) Run(ctx context.Context) error {
+	return p.loop(ctx, func(r Result) {
+		switch {
+		case r.Err != nil && p.OnError != nil:
+			p.OnError(r.Err)
+		case r.Err == nil && p.OnReadings != nil:
+			p.OnReadings(r.Readings)
+		}
+	})
+}
+
+// Stream polls until ctx is cancelled and delivers the results on the returned
+// channel, which is closed afterwards. Delivery blocks: a slow consumer delays
+// the next poll instead of results piling up. The callbacks are not used.
+func (p *Poller) Stream(ctx context.Context) (<-chan Result, error) {
+	if p.Interval <= 0 {
+		return nil, fmt.Errorf("invalid poll interval: %v", p.Interval)
+	}
+
+	results := make(chan Result)
+
+	go func() {
+		defer close(results)
+
+		_ = p.loop(ctx, func(r Result) {
+			select {
+			case results <- r:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return results, nil
+}
+
+// loop polls the gateway until ctx is cancelled, passing each result to deliver
+func (p *Poller) loop(ctx context.Context, deliver func(Result)) error {
+	if p.Interval <= 0 {
+		return fmt.Errorf("invalid poll interval: %v", p.Interval)
+	}
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if p.Jitter > 0 {
+			select {
+			case <-time.After(rand.N(p.Jitter)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		readings, err := p.Gateway.GetReadings()
+		deliver(Result{Time: time.Now(), Readings: readings, Err: err})
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package poll
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// gatewayFunc adapts a function to the emhcasa.Gateway interface
+type gatewayFunc func() ([]emhcasa.Reading, error)
+
+func (f gatewayFunc) GetReadings() ([]emhcasa.Reading, error) {
+	return f()
+}
+
+// TestPollerRun tests periodic polling with callbacks until cancellation
+func TestPollerRun(t *testing.T) {
+	var calls int
+	gw := gatewayFunc(func() ([]emhcasa.Reading, error) {
+		calls++
+		if calls%2 == 0 {
+			return nil, errors.New("gateway unavailable")
+		}
+		return []emhcasa.Reading{{OBIS: "16.7.0", Value: 500}}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var readings, errs int
+	p := &Poller{
+		Gateway:  gw,
+		Interval: time.Millisecond,
+		Jitter:   time.Millisecond,
+		OnReadings: func(r []emhcasa.Reading) {
+			if readings++; readings == 2 {
+				cancel()
+			}
+		},
+		OnError: func(err error) { errs++ },
+	}
+
+	if err := p.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+	if readings != 2 || errs != 1 {
+		t.Errorf("Run() delivered %d readings and %d errors, want 2 and 1", readings, errs)
+	}
+
+	if err := (&Poller{Gateway: gw}).Run(context.Background()); err == nil {
+		t.Error("Run() expected error for zero interval")
+	}
+}
+
+// TestPollerStream tests channel delivery and closing on cancellation
+func TestPollerStream(t *testing.T) {
+	gw := gatewayFunc(func() ([]emhcasa.Reading, error) {
+		return []emhcasa.Reading{{OBIS: "16.7.0", Value: 500}}, nil
+	})
+
+	p := &Poller{Gateway: gw, Interval: time.Millisecond}
+	if _, err := (&Poller{Gateway: gw}).Stream(context.Background()); err == nil {
+		t.Error("Stream() expected error for zero interval")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := p.Stream(ctx)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		r := <-results
+		if r.Err != nil || len(r.Readings) != 1 || r.Readings[0].Value != 500 {
+			t.Errorf("result %d = %+v", i, r)
+		}
+	}
+
+	cancel()
+	for range results {
+		// drain until closed
+	}
+}
@@ -0,0 +1,53 @@
+package emhcasa
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by RateLimited if a request is refused and no
+// readings from a previous request are available.
+var ErrRateLimited = errors.New("gateway rate limit exceeded")
+
+// RateLimited wraps a Gateway and enforces a minimum interval between requests,
+// regardless of how often it is called. CASA gateways may lock the HAN account
+// after request bursts. Calls within the interval return the readings of the
+// last successful request; check Reading.ReceivedAt for their age.
+type RateLimited struct {
+	gw       Gateway
+	interval time.Duration
+
+	mu       sync.Mutex
+	last     time.Time // last request, failed or not
+	readings []Reading // readings of the last successful request
+}
+
+// NewRateLimited wraps gw allowing at most one request per minInterval.
+func NewRateLimited(gw Gateway, minInterval time.Duration) *RateLimited {
+	return &RateLimited{gw: gw, interval: minInterval}
+}
+
+// GetReadings implements Gateway.
+func (r *RateLimited) GetReadings() ([]Reading, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.last.IsZero() && time.Since(r.last) < r.interval {
+		if r.readings == nil {
+			return nil, ErrRateLimited
+		}
+		return slices.Clone(r.readings), nil
+	}
+
+	r.last = time.Now()
+
+	readings, err := r.gw.GetReadings()
+	if err != nil {
+		return nil, err
+	}
+
+	r.readings = readings
+	return slices.Clone(readings), nil
+}
@@ -0,0 +1,53 @@
+package emhcasa
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// gatewayFunc adapts a function to the Gateway interface
+type gatewayFunc func() ([]Reading, error)
+
+func (f gatewayFunc) GetReadings() ([]Reading, error) {
+	return f()
+}
+
+// TestRateLimited tests that requests within the interval are served from cache
+func TestRateLimited(t *testing.T) {
+	var calls int
+	fail := true
+	gw := gatewayFunc(func() ([]Reading, error) {
+		calls++
+		if fail {
+			return nil, errors.New("connection refused")
+		}
+		return []Reading{{OBIS: "16.7.0", Value: float64(calls)}}, nil
+	})
+
+	rl := NewRateLimited(gw, 50*time.Millisecond)
+
+	if _, err := rl.GetReadings(); err == nil || errors.Is(err, ErrRateLimited) {
+		t.Fatalf("GetReadings() error = %v, want gateway error", err)
+	}
+	if _, err := rl.GetReadings(); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("GetReadings() error = %v, want ErrRateLimited", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	fail = false
+
+	for i := 0; i < 3; i++ {
+		readings, err := rl.GetReadings()
+		if err != nil {
+			t.Fatalf("GetReadings() error = %v", err)
+		}
+		if readings[0].Value != 2 {
+			t.Errorf("GetReadings() = %v, want cached value 2", readings[0].Value)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("gateway called %d times, want 2", calls)
+	}
+}
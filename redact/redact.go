@@ -0,0 +1,66 @@
+// Package redact pseudonymizes identifying data in diagnostics output.
+//
+// Meter IDs, serial numbers and IP addresses are replaced by stable keyed
+// pseudonyms (HMAC-SHA256), so shared logs and dumps stay comparable across
+// runs without revealing the original values. The owner of the key can
+// recompute the pseudonym of a known identifier to map it back.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+var (
+	// meter IDs per DIN 43863-5, e.g. 1EMH0012345678
+	reMeterID = regexp.MustCompile(`\b[0-9][A-Z]{3}[0-9A-F]{2}[0-9]{8}\b`)
+	reIPv4    = regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)
+	reIPv6    = regexp.MustCompile(`[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}(?:%[0-9A-Za-z_.-]+)?`)
+)
+
+// Redactor replaces identifiers with keyed pseudonyms.
+type Redactor struct {
+	key []byte
+}
+
+// New creates a Redactor using the given secret key.
+// The same key always yields the same pseudonyms.
+func New(key []byte) *Redactor {
+	return &Redactor{key: key}
+}
+
+// Pseudonym returns a stable pseudonym for id, prefixed with kind (e.g. "meter", "ip").
+func (r *Redactor) Pseudonym(kind, id string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(id))
+	return kind + "-" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// String scrubs meter IDs, IP addresses and any additional identifiers
+// (e.g. serial numbers or user names) from s.
+func (r *Redactor) String(s string, ids ...string) string {
+	for _, id := range ids {
+		if id != "" {
+			s = strings.ReplaceAll(s, id, r.Pseudonym("id", id))
+		}
+	}
+
+	s = reMeterID.ReplaceAllStringFunc(s, func(m string) string {
+		return r.Pseudonym("meter", m)
+	})
+
+	ip := func(m string) string {
+		if _, err := netip.ParseAddr(m); err != nil {
+			return m
+		}
+		return r.Pseudonym("ip", m)
+	}
+	s = reIPv4.ReplaceAllStringFunc(s, ip)
+	s = reIPv6.ReplaceAllStringFunc(s, ip)
+
+	return s
+}
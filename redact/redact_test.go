@@ -0,0 +1,83 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestString tests scrubbing of identifiers from text
+func TestString(t *testing.T) {
+	r := New([]byte("secret"))
+
+	tests := []struct {
+		name   string
+		input  string
+		ids    []string
+		hidden []string
+		kept   []string
+	}{
+		{
+			name:   "meter ID",
+			input:  `{"sensor_domains":["1EMH0012345678"]}`,
+			hidden: []string{"1EMH0012345678"},
+			kept:   []string{"sensor_domains"},
+		},
+		{
+			name:   "IPv4 address",
+			input:  "request to https://192.168.33.2/json failed",
+			hidden: []string{"192.168.33.2"},
+			kept:   []string{"https://", "/json failed"},
+		},
+		{
+			name:   "IPv6 link-local address with zone",
+			input:  "gateway at https://[fe80::dead:beef%eth0]",
+			hidden: []string{"fe80::dead:beef", "eth0"},
+			kept:   []string{"https://["},
+		},
+		{
+			name:   "additional identifiers",
+			input:  "serial SN-4711 user consumer01",
+			ids:    []string{"SN-4711", "consumer01"},
+			hidden: []string{"SN-4711", "consumer01"},
+			kept:   []string{"serial", "user"},
+		},
+		{
+			name:  "OBIS codes and timestamps untouched",
+			input: "16.7.0 = 2500 at 12:30:00",
+			kept:  []string{"16.7.0", "12:30:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.String(tt.input, tt.ids...)
+			for _, h := range tt.hidden {
+				if strings.Contains(got, h) {
+					t.Errorf("String() = %v, still contains %v", got, h)
+				}
+			}
+			for _, k := range tt.kept {
+				if !strings.Contains(got, k) {
+					t.Errorf("String() = %v, lost %v", got, k)
+				}
+			}
+		})
+	}
+}
+
+// TestPseudonym tests pseudonyms are stable per key
+func TestPseudonym(t *testing.T) {
+	a := New([]byte("secret")).Pseudonym("meter", "1EMH0012345678")
+	b := New([]byte("secret")).Pseudonym("meter", "1EMH0012345678")
+	c := New([]byte("other")).Pseudonym("meter", "1EMH0012345678")
+
+	if a != b {
+		t.Errorf("Pseudonym() not stable: %v != %v", a, b)
+	}
+	if a == c {
+		t.Errorf("Pseudonym() independent of key: %v", a)
+	}
+	if !strings.HasPrefix(a, "meter-") {
+		t.Errorf("Pseudonym() = %v, want meter- prefix", a)
+	}
+}
@@ -0,0 +1,109 @@
+package emhcasa
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures WithRetry. Zero values use the defaults noted per field.
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts including the first, default 3
+	InitialBackoff time.Duration // delay before the first retry, doubled per retry, default 1s
+	MaxBackoff     time.Duration // upper bound of the delay, default 30s
+	Timeout        time.Duration // per-attempt timeout, requires a Client, default none
+}
+
+// retryGateway implements WithRetry
+type retryGateway struct {
+	gw     Gateway
+	policy RetryPolicy
+}
+
+// WithRetry wraps gw retrying transient failures - network errors and 5xx
+// responses - with exponential backoff and jitter. Authentication failures and
+// other errors are returned immediately, so a wrong password doesn't lock the
+// HAN account.
+func WithRetry(gw Gateway, policy RetryPolicy) Gateway {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = time.Second
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 30 * time.Second
+	}
+
+	return &retryGateway{gw: gw, policy: policy}
+}
+
+// GetReadings implements Gateway.
+func (r *retryGateway) GetReadings() ([]Reading, error) {
+	backoff := r.policy.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		readings, err := r.attempt()
+		if err == nil || attempt == r.policy.MaxAttempts || !isTransient(err) {
+			return readings, err
+		}
+
+		// equal jitter: between half and the full backoff
+		time.Sleep(backoff/2 + rand.N(backoff/2+1))
+		backoff = min(2*backoff, r.policy.MaxBackoff)
+	}
+}
+
+// attempt reads the gateway once, applying the per-attempt timeout if supported
+func (r *retryGateway) attempt() ([]Reading, error) {
+	cg, ok := r.gw.(interface {
+		GetReadingsContext(context.Context) ([]Reading, error)
+	})
+	if !ok || r.policy.Timeout <= 0 {
+		return r.gw.GetReadings()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.policy.Timeout)
+	defer cancel()
+
+	return cg.GetReadingsContext(ctx)
+}
+
+// isTransient reports whether err may go away when retrying: timeouts,
+// failed connections and connections closed by the gateway. Certificate and
+// TLS failures as well as errors of the client itself are permanent.
+func isTransient(err error) bool {
+	if se := new(StatusError); errors.As(err, &se) {
+		return se.Code >= http.StatusInternalServerError
+	}
+
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostErr      x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &verifyErr) || errors.As(err, &authorityErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr) {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	if ne := net.Error(nil); errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+
+	// dial, read and write failures, but not TLS alerts
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op != "remote error" && oe.Op != "local error"
+}
@@ -0,0 +1,109 @@
+package emhcasa
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestWithRetry tests which errors are retried and how often
+func TestWithRetry(t *testing.T) {
+	netErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"success", nil, 1, false},
+		{"network error recovers", []error{netErr, netErr}, 3, false},
+		{"server error exhausts attempts", []error{&StatusError{500}, &StatusError{503}, &StatusError{502}}, 3, true},
+		{"auth failure not retried", []error{fmt.Errorf("failed: %w", &StatusError{401})}, 1, true},
+		{"parse error not retried", []error{errors.New("no valid meter values found")}, 1, true},
+		{"connection reset recovers", []error{&url.Error{Op: "Get", Err: syscall.ECONNRESET}}, 2, false},
+		{"credentials failure not retried", []error{&url.Error{Op: "Get", Err: errors.New("failed to get credentials")}}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			gw := gatewayFunc(func() ([]Reading, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return nil, tt.errs[calls-1]
+				}
+				return []Reading{{OBIS: "16.7.0"}}, nil
+			})
+
+			_, err := WithRetry(gw, RetryPolicy{InitialBackoff: time.Millisecond}).GetReadings()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetReadings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("gateway called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// TestWithRetryTimeout tests the per-attempt timeout with a Client
+func TestWithRetryTimeout(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	gw := WithRetry(c, RetryPolicy{InitialBackoff: time.Millisecond, Timeout: 50 * time.Millisecond})
+	readings, err := gw.GetReadings()
+	if err != nil {
+		t.Fatalf("GetReadings() error = %v", err)
+	}
+	if len(readings) != 1 || readings[0].Value != 2500 {
+		t.Errorf("GetReadings() = %+v", readings)
+	}
+}
+
+// TestWithRetryCertificate tests that certificate verification failures are not retried
+func TestWithRetryCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	c.SetRootCAs(x509.NewCertPool())
+
+	var calls int
+	gw := gatewayFunc(func() ([]Reading, error) {
+		calls++
+		return c.GetReadings()
+	})
+
+	if _, err := WithRetry(gw, RetryPolicy{InitialBackoff: time.Millisecond}).GetReadings(); err == nil {
+		t.Fatal("GetReadings() expected certificate error")
+	}
+	if calls != 1 {
+		t.Errorf("gateway called %d times, want 1", calls)
+	}
+}
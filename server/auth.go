@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Auth configures client authentication of the built-in servers. Clients
+// authenticate with any of the bearer tokens or the basic auth credentials.
+// The zero value disables authentication.
+type Auth struct {
+	Tokens   []string // accepted bearer tokens
+	User     string   // basic auth user, empty to disable basic auth
+	Password string   // basic auth password
+}
+
+// Enabled reports whether any authentication method is configured.
+func (a Auth) Enabled() bool {
+	return a.hasTokens() || a.User != ""
+}
+
+// RequireAuth returns a handler that responds with 401 Unauthorized to
+// requests not authenticated per a, and passes the others to h. Tokens are
+// also accepted in the access_token query parameter, for WebSocket and
+// EventSource clients in browsers, which cannot set headers. If a is not
+// enabled, h is returned unchanged.
+func RequireAuth(h http.Handler, a Auth) http.Handler {
+	if !a.Enabled() {
+		return h
+	}
+
+	var challenge []string
+	if a.hasTokens() {
+		challenge = append(challenge, `Bearer realm="smgw"`)
+	}
+	if a.User != "" {
+		challenge = append(challenge, `Basic realm="smgw"`)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.authorized(r) {
+			for _, c := range challenge {
+				w.Header().Add("WWW-Authenticate", c)
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authorized checks the credentials of r in constant time
+func (a Auth) authorized(r *http.Request) bool {
+	if user, password, ok := r.BasicAuth(); ok {
+		return a.User != "" && equal(user, a.User)&equal(password, a.Password) == 1
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		return false
+	}
+
+	for _, t := range a.Tokens {
+		if t != "" && equal(token, t) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares two strings in constant time, returning 1 if they are equal
+func equal(a, b string) int {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b))
+}
+
+// hasTokens reports whether any non-empty token is configured
+func (a Auth) hasTokens() bool {
+	return slices.ContainsFunc(a.Tokens, func(t string) bool { return t != "" })
+}
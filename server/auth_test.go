@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRequireAuth tests bearer tokens, basic auth and the disabled case
+func TestRequireAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := RequireAuth(ok, Auth{Tokens: []string{"", "secret"}, User: "admin", Password: "pw"})
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		user   string
+		pass   string
+		want   int
+	}{
+		{"none", "/", "", "", "", http.StatusUnauthorized},
+		{"bearer", "/", "Bearer secret", "", "", http.StatusOK},
+		{"wrong bearer", "/", "Bearer other", "", "", http.StatusUnauthorized},
+		{"empty bearer", "/", "Bearer ", "", "", http.StatusUnauthorized},
+		{"query", "/?access_token=secret", "", "", "", http.StatusOK},
+		{"basic", "/", "", "admin", "pw", http.StatusOK},
+		{"wrong password", "/", "", "admin", "secret", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, tt.pass)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusUnauthorized && len(rec.Header().Values("WWW-Authenticate")) != 2 {
+			t.Errorf("%s: WWW-Authenticate = %q", tt.name, rec.Header().Values("WWW-Authenticate"))
+		}
+	}
+
+	if (Auth{Tokens: []string{""}}).Enabled() {
+		t.Error("Auth with empty token is enabled")
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health tracks the polls of gateways for liveness and readiness probes of
+// container orchestrators and Docker healthchecks:
+//
+//	/healthz  200 while every gateway was polled within MaxAge, successful
+//	          or not; 503 if polling wedged and the process should restart
+//	/readyz   200 while every gateway was polled successfully within MaxAge;
+//	          503 while a gateway is unreachable
+//
+// Both respond with the state of each gateway as JSON.
+type Health struct {
+	maxAge  time.Duration
+	started time.Time
+
+	mu       sync.Mutex
+	gateways map[string]*GatewayHealth
+}
+
+// GatewayHealth is the poll state of a gateway.
+type GatewayHealth struct {
+	LastPoll    time.Time `json:"last_poll,omitzero"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// NewHealth creates a Health for the named gateways. maxAge is typically a
+// few poll intervals; 0 disables the age checks, so a gateway is ready while
+// its last poll succeeded.
+func NewHealth(maxAge time.Duration, gateways ...string) *Health {
+	h := &Health{maxAge: maxAge, started: time.Now(), gateways: make(map[string]*GatewayHealth)}
+	for _, name := range gateways {
+		h.gateways[name] = &GatewayHealth{}
+	}
+	return h
+}
+
+// Observe records the result of a poll, e.g. from poll.Poller callbacks.
+// Unknown gateways are added.
+func (h *Health) Observe(gateway string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	g, ok := h.gateways[gateway]
+	if !ok {
+		g = &GatewayHealth{}
+		h.gateways[gateway] = g
+	}
+
+	g.LastPoll = time.Now()
+	g.Error = ""
+	if err != nil {
+		g.Error = err.Error()
+		return
+	}
+	g.LastSuccess = g.LastPoll
+}
+
+// Live reports whether all gateways were polled recently. Gateways not
+// polled yet count as polled at the creation of h.
+func (h *Health) Live() bool {
+	live, _ := h.check()
+	return live
+}
+
+// Ready reports whether all gateways were polled successfully recently.
+func (h *Health) Ready() bool {
+	_, ready := h.check()
+	return ready
+}
+
+// check returns liveness and readiness
+func (h *Health) check() (live, ready bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	live, ready = true, true
+	for _, g := range h.gateways {
+		lastPoll := g.LastPoll
+		if lastPoll.IsZero() {
+			lastPoll = h.started
+		}
+
+		if !h.recent(lastPoll) {
+			live = false
+		}
+		if g.Error != "" || g.LastSuccess.IsZero() || !h.recent(g.LastSuccess) {
+			ready = false
+		}
+	}
+
+	return live, ready
+}
+
+// recent reports whether t is within maxAge
+func (h *Health) recent(t time.Time) bool {
+	return h.maxAge <= 0 || time.Since(t) <= h.maxAge
+}
+
+// Handle registers the /healthz and /readyz endpoints on mux.
+func (h *Health) Handle(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", h.ServeLiveness)
+	mux.HandleFunc("GET /readyz", h.ServeReadiness)
+}
+
+// ServeLiveness responds to liveness probes.
+func (h *Health) ServeLiveness(w http.ResponseWriter, r *http.Request) {
+	live, _ := h.check()
+	h.write(w, live)
+}
+
+// ServeReadiness responds to readiness probes.
+func (h *Health) ServeReadiness(w http.ResponseWriter, r *http.Request) {
+	_, ready := h.check()
+	h.write(w, ready)
+}
+
+// write writes the status and state of all gateways
+func (h *Health) write(w http.ResponseWriter, ok bool) {
+	h.mu.Lock()
+	gateways := make(map[string]GatewayHealth, len(h.gateways))
+	for name, g := range h.gateways {
+		gateways[name] = *g
+	}
+	h.mu.Unlock()
+
+	status, code := "ok", http.StatusOK
+	if !ok {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(struct {
+		Status   string                   `json:"status"`
+		Gateways map[string]GatewayHealth `json:"gateways"`
+	}{status, gateways})
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestHealth tests liveness and readiness by poll results and age
+func TestHealth(t *testing.T) {
+	h := NewHealth(time.Hour, "home", "garage")
+	mux := http.NewServeMux()
+	h.Handle(mux)
+
+	probe := func(path string) (int, map[string]GatewayHealth) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var res struct {
+			Gateways map[string]GatewayHealth `json:"gateways"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: Unmarshal() error = %v", path, err)
+		}
+		return rec.Code, res.Gateways
+	}
+
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("healthz before polling = %d", code)
+	}
+	if code, _ := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before polling = %d", code)
+	}
+
+	h.Observe("home", nil)
+	h.Observe("garage", errors.New("timeout"))
+	if code, gw := probe("/readyz"); code != http.StatusServiceUnavailable || gw["garage"].Error != "timeout" || gw["home"].LastSuccess.IsZero() {
+		t.Errorf("readyz with failing gateway = %d %+v", code, gw)
+	}
+
+	h.Observe("garage", nil)
+	if !h.Ready() || !h.Live() {
+		t.Errorf("Ready() = %v, Live() = %v after successful polls", h.Ready(), h.Live())
+	}
+
+	// polls older than maxAge
+	h.mu.Lock()
+	h.gateways["home"].LastPoll = time.Now().Add(-2 * time.Hour)
+	h.gateways["home"].LastSuccess = time.Now().Add(-2 * time.Hour)
+	h.mu.Unlock()
+
+	if code, _ := probe("/healthz"); code != http.StatusServiceUnavailable || h.Ready() {
+		t.Errorf("healthz with stale poll = %d, Ready() = %v", code, h.Ready())
+	}
+}
+
+// TestHealthFailure tests that a gateway failing after a successful poll is not ready
+func TestHealthFailure(t *testing.T) {
+	for _, maxAge := range []time.Duration{0, time.Hour} {
+		h := NewHealth(maxAge, "home")
+
+		h.Observe("home", nil)
+		if !h.Ready() {
+			t.Errorf("maxAge %v: Ready() = false after successful poll", maxAge)
+		}
+
+		h.Observe("home", errors.New("unreachable"))
+		if h.Ready() || !h.Live() {
+			t.Errorf("maxAge %v: Ready() = %v, Live() = %v after failed poll, want not ready but live", maxAge, h.Ready(), h.Live())
+		}
+
+		h.Observe("home", nil)
+		if !h.Ready() {
+			t.Errorf("maxAge %v: Ready() = false after recovery", maxAge)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// maxRPCBody limits the size of JSON-RPC requests
+const maxRPCBody = 1 << 20
+
+// rpcRequest is a JSON-RPC 2.0 request; requests without ID are notifications
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcError is a JSON-RPC 2.0 error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// readingsParams are the parameters of getReadings
+type readingsParams struct {
+	Gateway string   `json:"gateway"`
+	OBIS    []string `json:"obis"`
+}
+
+// jsonrpc serves JSON-RPC 2.0 requests and batches with the methods
+// getGateways, getReadings and getHistory
+func (s *Server) jsonrpc(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRPCBody))
+	if err != nil {
+		writeJSON(w, http.StatusOK, rpcFailure(nil, rpcParseError, err.Error()))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeJSON(w, http.StatusOK, rpcFailure(nil, rpcParseError, err.Error()))
+			return
+		}
+		if len(batch) == 0 {
+			writeJSON(w, http.StatusOK, rpcFailure(nil, rpcInvalidRequest, "empty batch"))
+			return
+		}
+
+		res := []rpcResponse{}
+		for _, raw := range batch {
+			if resp, ok := s.rpcCall(raw); ok {
+				res = append(res, resp)
+			}
+		}
+
+		if len(res) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	resp, ok := s.rpcCall(body)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// rpcCall executes a single request. It returns false for notifications.
+func (s *Server) rpcCall(raw json.RawMessage) (rpcResponse, bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return rpcFailure(nil, rpcParseError, err.Error()), true
+		}
+		return rpcFailure(nil, rpcInvalidRequest, err.Error()), true
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcFailure(req.ID, rpcInvalidRequest, "invalid request"), true
+	}
+
+	result, err := s.rpcMethod(req.Method, req.Params)
+
+	if req.ID == nil {
+		return rpcResponse{}, false
+	}
+
+	var rpcErr *rpcError
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcFailure(req.ID, rpcErr.Code, rpcErr.Message), true
+	case err != nil && statusCode(err) == http.StatusInternalServerError:
+		return rpcFailure(req.ID, rpcServerError, err.Error()), true
+	case err != nil:
+		return rpcFailure(req.ID, rpcInvalidParams, err.Error()), true
+	}
+
+	return rpcResponse{JSONRPC: "2.0", Result: result, ID: req.ID}, true
+}
+
+// rpcMethod dispatches a method call
+func (s *Server) rpcMethod(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "getGateways":
+		return s.gatewayList(), nil
+
+	case "getReadings":
+		var p readingsParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.currentReadings(p.Gateway, p.OBIS)
+
+	case "getHistory":
+		var q historyQuery
+		if err := decodeParams(params, &q); err != nil {
+			return nil, err
+		}
+		return s.querySeries(q)
+	}
+
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + method}
+}
+
+// decodeParams decodes named parameters, which may be omitted
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// rpcFailure returns an error response
+func rpcFailure(id json.RawMessage, code int, msg string) rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: code, Message: msg}, ID: id}
+}
@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// TestJSONRPC tests JSON-RPC calls, batches and errors
+func TestJSONRPC(t *testing.T) {
+	home := gatewayFunc(func() ([]emhcasa.Reading, error) {
+		return []emhcasa.Reading{
+			{OBIS: "1.8.0", Value: 100, Unit: emhcasa.UnitWattHour},
+			{OBIS: "16.7.0", Value: 500, Unit: emhcasa.UnitWatt},
+		}, nil
+	})
+	srv := New(map[string]emhcasa.Gateway{"home": home}, nil)
+
+	call := func(body string) (int, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/jsonrpc", strings.NewReader(body)))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	code, body := call(`{"jsonrpc":"2.0","method":"getReadings","params":{"gateway":"home","obis":["1.8.0"]},"id":1}`)
+	var resp struct {
+		Result []Readings `json:"result"`
+		ID     int        `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if code != http.StatusOK || resp.ID != 1 || len(resp.Result) != 1 || len(resp.Result[0].Readings) != 1 ||
+		resp.Result[0].Readings[0].OBIS != "1.8.0" {
+		t.Errorf("getReadings = %d %s", code, body)
+	}
+
+	for _, tc := range []struct {
+		name, body string
+		code       int
+		want       string
+	}{
+		{"gateways", `{"jsonrpc":"2.0","method":"getGateways","id":"a"}`, http.StatusOK,
+			`{"jsonrpc":"2.0","result":[{"name":"home"}],"id":"a"}`},
+		{"notification", `{"jsonrpc":"2.0","method":"getGateways"}`, http.StatusNoContent, ``},
+		{"unknown method", `{"jsonrpc":"2.0","method":"reboot","id":2}`, http.StatusOK,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: reboot"},"id":2}`},
+		{"unknown gateway", `{"jsonrpc":"2.0","method":"getReadings","params":{"gateway":"x"},"id":3}`, http.StatusOK,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"unknown gateway: x"},"id":3}`},
+		{"no history", `{"jsonrpc":"2.0","method":"getHistory","params":{"obis":"1.8.0"},"id":4}`, http.StatusOK,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"history not available"},"id":4}`},
+		{"invalid request", `{"method":"getGateways","id":5}`, http.StatusOK,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":5}`},
+		{"parse error", `{"jsonrpc":`, http.StatusOK,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"unexpected end of JSON input"},"id":null}`},
+		{"batch", `[{"jsonrpc":"2.0","method":"getGateways","id":1},{"jsonrpc":"2.0","method":"getGateways"},1]`, http.StatusOK,
+			`[{"jsonrpc":"2.0","result":[{"name":"home"}],"id":1},` +
+				`{"jsonrpc":"2.0","error":{"code":-32600,"message":"json: cannot unmarshal number into Go value of type rest.rpcRequest"},"id":null}]`},
+		{"empty batch", `[]`, http.StatusOK,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`},
+	} {
+		if code, body := call(tc.body); code != tc.code || body != tc.want {
+			t.Errorf("%s = %d %s, want %d %s", tc.name, code, body, tc.code, tc.want)
+		}
+	}
+}
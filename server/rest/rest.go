@@ -0,0 +1,376 @@
+// Package rest serves gateway readings and stored history as a JSON HTTP API.
+//
+// Endpoints (GET unless noted):
+//
+//	/api/v1/health                  liveness, always {"status":"ok"}
+//	/healthz, /readyz               liveness and readiness by poll age, see server.Health
+//	/api/v1/gateways                configured gateways and their meter IDs
+//	/api/v1/readings                current readings, ?gateway=name&obis=pattern,...
+//	/api/v1/entities                Home Assistant sensor metadata of current readings, same parameters
+//	/api/v1/history                 stored series, ?obis=code&from=RFC3339&to=RFC3339&step=duration
+//	/api/v1/stream                  Server-Sent Events of published readings, ?gateway=name&obis=pattern,...
+//	/api/v1/ws                      WebSocket messages of published readings, same parameters
+//	/api/v1/jsonrpc (POST)          JSON-RPC 2.0 methods getGateways, getReadings, getHistory
+//
+// Readings use the JSON encoding of emhcasa.Reading, history the encoding of
+// store.Series. Errors are returned as {"error":"..."}. Streaming clients
+// receive the Readings passed to Server.Publish. The JSON-RPC methods take
+// the query parameters as named params ("obis" as array for getReadings)
+// and return the same results as the corresponding endpoints.
+package rest
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/homeassistant"
+	"github.com/iseeberg79/emh-casa-go/obis"
+	"github.com/iseeberg79/emh-casa-go/server"
+	"github.com/iseeberg79/emh-casa-go/store"
+	"golang.org/x/net/websocket"
+)
+
+// DefaultHistory is the time range returned by /api/v1/history without from.
+const DefaultHistory = 24 * time.Hour
+
+// DefaultMaxAge is the age of the last published poll after which a gateway
+// is reported unhealthy, unless replaced by SetHealth.
+const DefaultMaxAge = 5 * time.Minute
+
+// Server is an http.Handler serving the REST API.
+// Gateways are read on each request; wrap them with emhcasa.NewRateLimited
+// to protect the gateway from frequent clients.
+type Server struct {
+	gateways map[string]emhcasa.Gateway
+	store    *store.Store
+	mux      *http.ServeMux
+	auth     server.Auth
+	tls      *tls.Config
+
+	mu   sync.Mutex                 // guards subs, last and polls
+	subs map[chan Readings]struct{} // streaming clients
+	last map[string]Readings        // latest published update per gateway
+
+	polls *server.Health // results passed to Publish
+}
+
+// Gateway describes a configured gateway.
+type Gateway struct {
+	Name    string `json:"name"`
+	MeterID string `json:"meter_id,omitempty"`
+}
+
+// Readings are the current readings of a gateway, or the error reading it.
+type Readings struct {
+	Gateway  string            `json:"gateway"`
+	Readings []emhcasa.Reading `json:"readings"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// Entities are the Home Assistant entities of a gateway's current readings.
+type Entities struct {
+	Gateway  string                 `json:"gateway"`
+	Entities []homeassistant.Entity `json:"entities"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// New creates a Server for the given gateways, keyed by name. st may be nil,
+// in which case /api/v1/history responds with 404.
+func New(gateways map[string]emhcasa.Gateway, st *store.Store) *Server {
+	s := &Server{
+		gateways: gateways,
+		store:    st,
+		mux:      http.NewServeMux(),
+		subs:     make(map[chan Readings]struct{}),
+		last:     make(map[string]Readings),
+		polls:    server.NewHealth(DefaultMaxAge, slices.Sorted(maps.Keys(gateways))...),
+	}
+
+	s.mux.HandleFunc("GET /api/v1/health", s.liveness)
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { s.pollHealth().ServeLiveness(w, r) })
+	s.mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) { s.pollHealth().ServeReadiness(w, r) })
+	s.mux.HandleFunc("GET /api/v1/gateways", s.listGateways)
+	s.mux.HandleFunc("GET /api/v1/readings", s.readings)
+	s.mux.HandleFunc("GET /api/v1/entities", s.entities)
+	s.mux.HandleFunc("GET /api/v1/history", s.history)
+	s.mux.HandleFunc("GET /api/v1/stream", s.streamEvents)
+	s.mux.Handle("GET /api/v1/ws", websocket.Server{Handler: s.streamWebSocket})
+	s.mux.HandleFunc("POST /api/v1/jsonrpc", s.jsonrpc)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// SetAuth requires clients of ListenAndServe to authenticate, except for
+// the /healthz and /readyz probes. When serving the API with another
+// http.Server, wrap it with server.RequireAuth instead.
+func (s *Server) SetAuth(auth server.Auth) {
+	s.auth = auth
+}
+
+// SetTLSConfig makes ListenAndServe serve HTTPS with the certificates of config.
+func (s *Server) SetTLSConfig(config *tls.Config) {
+	s.tls = config
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled and returns ctx.Err().
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/", server.RequireAuth(s, s.auth))
+	mux.Handle("GET /healthz", s)
+	mux.Handle("GET /readyz", s)
+
+	return server.ListenAndServeTLS(ctx, addr, mux, s.tls)
+}
+
+// SetHealth replaces the tracker of published poll results served at
+// /healthz and /readyz, e.g. with server.NewHealth(3*interval, names...) so
+// readiness requires recent successful polls of all gateways. By default a
+// gateway is ready while its last poll, published within DefaultMaxAge,
+// succeeded.
+func (s *Server) SetHealth(h *server.Health) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.polls = h
+}
+
+// pollHealth returns the poll result tracker
+func (s *Server) pollHealth() *server.Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.polls
+}
+
+// liveness responds to liveness checks
+func (s *Server) liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// listGateways lists the configured gateways with their meter IDs
+func (s *Server) listGateways(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.gatewayList())
+}
+
+// readings reads the selected gateways and filters their readings by OBIS patterns
+func (s *Server) readings(w http.ResponseWriter, r *http.Request) {
+	res, err := s.currentReadings(r.URL.Query().Get("gateway"), parseFilter(r))
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// entities maps the current readings of the selected gateways to Home
+// Assistant entities, identified by meter ID if known. Unknown registers are
+// omitted.
+func (s *Server) entities(w http.ResponseWriter, r *http.Request) {
+	res, err := s.currentReadings(r.URL.Query().Get("gateway"), parseFilter(r))
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	devices := make(map[string]string)
+	for _, g := range s.gatewayList() {
+		devices[g.Name] = cmp.Or(g.MeterID, g.Name)
+	}
+
+	list := make([]Entities, 0, len(res))
+	for _, gw := range res {
+		e := Entities{Gateway: gw.Gateway, Entities: []homeassistant.Entity{}, Error: gw.Error}
+		for _, reading := range gw.Readings {
+			if entity, ok := homeassistant.NewEntity(devices[gw.Gateway], reading.OBIS); ok {
+				e.Entities = append(e.Entities, entity)
+			}
+		}
+		list = append(list, e)
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// history returns the stored series of an OBIS code, optionally resampled
+func (s *Server) history(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	series, err := s.querySeries(historyQuery{OBIS: q.Get("obis"), From: q.Get("from"), To: q.Get("to"), Step: q.Get("step")})
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, series)
+}
+
+var (
+	errUnknownGateway = errors.New("unknown gateway")
+	errNoHistory      = errors.New("history not available")
+)
+
+// paramError is returned for missing or invalid request parameters
+type paramError struct {
+	msg string
+}
+
+func (e *paramError) Error() string {
+	return e.msg
+}
+
+// statusCode returns the HTTP status code of an error
+func statusCode(err error) int {
+	var pe *paramError
+	switch {
+	case errors.As(err, &pe):
+		return http.StatusBadRequest
+	case errors.Is(err, errUnknownGateway), errors.Is(err, errNoHistory):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// gatewayList returns the configured gateways with their meter IDs
+func (s *Server) gatewayList() []Gateway {
+	res := []Gateway{}
+	for _, name := range slices.Sorted(maps.Keys(s.gateways)) {
+		g := Gateway{Name: name}
+		if m, ok := s.gateways[name].(interface{ MeterID() (string, error) }); ok {
+			g.MeterID, _ = m.MeterID()
+		}
+		res = append(res, g)
+	}
+	return res
+}
+
+// currentReadings reads the named gateway, or all if gateway is empty, and
+// filters the readings. Gateway failures are reported per gateway.
+func (s *Server) currentReadings(gateway string, filter obis.Filter) ([]Readings, error) {
+	names := slices.Sorted(maps.Keys(s.gateways))
+	if gateway != "" {
+		if _, ok := s.gateways[gateway]; !ok {
+			return nil, fmt.Errorf("%w: %s", errUnknownGateway, gateway)
+		}
+		names = []string{gateway}
+	}
+
+	res := []Readings{}
+	for _, name := range names {
+		readings, err := s.gateways[name].GetReadings()
+		if err != nil {
+			res = append(res, Readings{Gateway: name, Readings: []emhcasa.Reading{}, Error: err.Error()})
+			continue
+		}
+
+		res = append(res, Readings{Gateway: name, Readings: filterReadings(readings, filter)})
+	}
+
+	return res, nil
+}
+
+// historyQuery holds the parameters of a history request
+type historyQuery struct {
+	OBIS string `json:"obis"`
+	From string `json:"from"` // RFC 3339, default To minus DefaultHistory
+	To   string `json:"to"`   // RFC 3339, default now
+	Step string `json:"step"` // resampling window, e.g. "15m", default none
+}
+
+// querySeries returns the stored series selected by q
+func (s *Server) querySeries(q historyQuery) (store.Series, error) {
+	if s.store == nil {
+		return store.Series{}, errNoHistory
+	}
+
+	if q.OBIS == "" {
+		return store.Series{}, &paramError{"missing obis parameter"}
+	}
+
+	to, err := parseTime(q.To, time.Now())
+	if err != nil {
+		return store.Series{}, &paramError{fmt.Sprintf("invalid to: %v", err)}
+	}
+
+	from, err := parseTime(q.From, to.Add(-DefaultHistory))
+	if err != nil {
+		return store.Series{}, &paramError{fmt.Sprintf("invalid from: %v", err)}
+	}
+
+	var step time.Duration
+	if q.Step != "" {
+		if step, err = time.ParseDuration(q.Step); err != nil || step <= 0 {
+			return store.Series{}, &paramError{fmt.Sprintf("invalid step: %s", q.Step)}
+		}
+	}
+
+	series, err := s.store.Series(q.OBIS, from, to)
+	if err != nil {
+		return store.Series{}, err
+	}
+
+	if step > 0 {
+		series = series.Resample(step)
+	}
+
+	if series.Values == nil {
+		series.Values = []store.TimedValue{}
+	}
+
+	return series, nil
+}
+
+// parseFilter returns the comma-separated obis patterns of the request, nil if none
+func parseFilter(r *http.Request) obis.Filter {
+	if patterns := r.URL.Query().Get("obis"); patterns != "" {
+		return strings.Split(patterns, ",")
+	}
+	return nil
+}
+
+// filterReadings returns the readings matching filter, all if filter is nil
+func filterReadings(readings []emhcasa.Reading, filter obis.Filter) []emhcasa.Reading {
+	res := []emhcasa.Reading{}
+	for _, r := range readings {
+		if filter == nil || filter.Match(r.OBIS) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// parseTime parses an RFC 3339 time, returning def if s is empty
+func parseTime(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// writeJSON writes v as JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
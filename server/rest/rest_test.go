@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/server"
+	"github.com/iseeberg79/emh-casa-go/store"
+)
+
+// gatewayFunc adapts a function to the emhcasa.Gateway interface
+type gatewayFunc func() ([]emhcasa.Reading, error)
+
+func (f gatewayFunc) GetReadings() ([]emhcasa.Reading, error) {
+	return f()
+}
+
+// get requests path from the server and decodes the JSON response into v
+func get(t *testing.T, h http.Handler, path string, v any) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s: Content-Type = %q", path, ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s: Unmarshal() error = %v", path, err)
+	}
+
+	return rec.Code
+}
+
+// TestServer tests the REST endpoints
+func TestServer(t *testing.T) {
+	home := gatewayFunc(func() ([]emhcasa.Reading, error) {
+		return []emhcasa.Reading{
+			{OBIS: "1.8.0", Value: 100, Unit: emhcasa.UnitWattHour},
+			{OBIS: "16.7.0", Value: 500, Unit: emhcasa.UnitWatt},
+		}, nil
+	})
+	broken := gatewayFunc(func() ([]emhcasa.Reading, error) {
+		return nil, errors.New("gateway unavailable")
+	})
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "readings.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	err = st.Add([]emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 99, Unit: emhcasa.UnitWattHour, Timestamp: now.Add(-2 * time.Hour)},
+		{OBIS: "1.8.0", Value: 100, Unit: emhcasa.UnitWattHour, Timestamp: now.Add(-time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	srv := New(map[string]emhcasa.Gateway{"home": home, "broken": broken}, st)
+
+	var health map[string]string
+	if code := get(t, srv, "/api/v1/health", &health); code != http.StatusOK || health["status"] != "ok" {
+		t.Errorf("health = %d %v", code, health)
+	}
+
+	if code := get(t, srv, "/readyz", &map[string]any{}); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before Publish() = %d", code)
+	}
+	srv.Publish("home", nil, nil)
+	if code := get(t, srv, "/readyz", &map[string]any{}); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before Publish() of all gateways = %d", code)
+	}
+	srv.Publish("broken", nil, nil)
+	if code := get(t, srv, "/readyz", &map[string]any{}); code != http.StatusOK {
+		t.Errorf("readyz = %d", code)
+	}
+	srv.Publish("broken", nil, errors.New("gateway unavailable"))
+	if code := get(t, srv, "/readyz", &map[string]any{}); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz after failed poll = %d", code)
+	}
+	srv.SetHealth(server.NewHealth(time.Hour, "home", "broken"))
+	if code := get(t, srv, "/readyz", &map[string]any{}); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz without polls = %d", code)
+	}
+
+	var gateways []Gateway
+	if code := get(t, srv, "/api/v1/gateways", &gateways); code != http.StatusOK || len(gateways) != 2 || gateways[0].Name != "broken" {
+		t.Errorf("gateways = %d %+v", code, gateways)
+	}
+
+	var readings []Readings
+	if code := get(t, srv, "/api/v1/readings", &readings); code != http.StatusOK || len(readings) != 2 ||
+		readings[0].Error != "gateway unavailable" || len(readings[1].Readings) != 2 {
+		t.Errorf("readings = %d %+v", code, readings)
+	}
+
+	if code := get(t, srv, "/api/v1/readings?gateway=home&obis=*.7.0", &readings); code != http.StatusOK || len(readings) != 1 ||
+		len(readings[0].Readings) != 1 || readings[0].Readings[0].OBIS != "16.7.0" {
+		t.Errorf("filtered readings = %d %+v", code, readings)
+	}
+
+	var entities []Entities
+	if code := get(t, srv, "/api/v1/entities?gateway=home", &entities); code != http.StatusOK || len(entities) != 1 ||
+		len(entities[0].Entities) != 2 || entities[0].Entities[0].UniqueID != "smgw_home_1_8_0" ||
+		entities[0].Entities[0].StateClass != "total_increasing" || entities[0].Entities[1].DeviceClass != "power" {
+		t.Errorf("entities = %d %+v", code, entities)
+	}
+
+	var errResp map[string]string
+	if code := get(t, srv, "/api/v1/readings?gateway=other", &errResp); code != http.StatusNotFound || errResp["error"] == "" {
+		t.Errorf("unknown gateway = %d %v", code, errResp)
+	}
+
+	var series store.Series
+	if code := get(t, srv, "/api/v1/history?obis=1.8.0", &series); code != http.StatusOK || series.Unit != "kWh" || len(series.Values) != 2 {
+		t.Errorf("history = %d %+v", code, series)
+	}
+
+	from := now.Add(-90 * time.Minute).Format(time.RFC3339)
+	if code := get(t, srv, "/api/v1/history?obis=1.8.0&step=1h&from="+from, &series); code != http.StatusOK || len(series.Values) != 1 || series.Values[0].Value != 100 {
+		t.Errorf("resampled history = %d %+v", code, series)
+	}
+
+	for _, path := range []string{"/api/v1/history", "/api/v1/history?obis=1.8.0&from=yesterday", "/api/v1/history?obis=1.8.0&step=0"} {
+		if code := get(t, srv, path, &errResp); code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want %d", path, code, http.StatusBadRequest)
+		}
+	}
+
+	if code := get(t, New(nil, nil), "/api/v1/history?obis=1.8.0", &errResp); code != http.StatusNotFound {
+		t.Errorf("history without store = %d, want %d", code, http.StatusNotFound)
+	}
+}
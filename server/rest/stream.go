@@ -0,0 +1,153 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"golang.org/x/net/websocket"
+)
+
+// streamBuffer is the number of updates buffered per streaming client.
+// Updates for clients that fall further behind are dropped.
+const streamBuffer = 16
+
+// keepAlive is the interval of SSE comments keeping idle connections open
+const keepAlive = 30 * time.Second
+
+// Publish pushes the result of a poll to all streaming clients, e.g. from
+// poll.Poller callbacks. The latest result per gateway is sent to clients
+// when they connect. The result is also recorded for /healthz and /readyz.
+func (s *Server) Publish(gateway string, readings []emhcasa.Reading, err error) {
+	s.pollHealth().Observe(gateway, err)
+
+	update := Readings{Gateway: gateway, Readings: readings}
+	if err != nil {
+		update.Error = err.Error()
+	}
+	if update.Readings == nil {
+		update.Readings = []emhcasa.Reading{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last[gateway] = update
+
+	for ch := range s.subs {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// subscribe registers a streaming client, which receives the latest
+// update per gateway first
+func (s *Server) subscribe() (<-chan Readings, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan Readings, streamBuffer+len(s.last))
+	for _, name := range slices.Sorted(maps.Keys(s.last)) {
+		ch <- s.last[name]
+	}
+	s.subs[ch] = struct{}{}
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs, ch)
+	}
+}
+
+// updates delivers the updates selected by the gateway and obis query
+// parameters of r until ctx is cancelled or a write fails. keepalive, if
+// not nil, is called when no update was sent for the keepAlive interval.
+func (s *Server) updates(ctx context.Context, r *http.Request, send func(Readings) error, keepalive func() error) {
+	gateway := r.URL.Query().Get("gateway")
+	filter := parseFilter(r)
+
+	ch, unsubscribe := s.subscribe()
+	defer unsubscribe()
+
+	timer := time.NewTimer(keepAlive)
+	defer timer.Stop()
+
+	for {
+		var err error
+
+		select {
+		case update := <-ch:
+			if gateway != "" && update.Gateway != gateway {
+				continue
+			}
+			update.Readings = filterReadings(update.Readings, filter)
+			err = send(update)
+		case <-timer.C:
+			if keepalive != nil {
+				err = keepalive()
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		if err != nil {
+			return
+		}
+		timer.Reset(keepAlive)
+	}
+}
+
+// streamEvents sends updates as Server-Sent Events of type "readings"
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	write := func(format string, args ...any) error {
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	s.updates(r.Context(), r, func(update Readings) error {
+		b, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		return write("event: readings\ndata: %s\n\n", b)
+	}, func() error {
+		return write(": keepalive\n\n")
+	})
+}
+
+// streamWebSocket sends updates as JSON text messages over a WebSocket connection
+func (s *Server) streamWebSocket(ws *websocket.Conn) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+
+	// messages from the client are ignored, reading detects the close
+	go func() {
+		defer cancel()
+
+		var msg string
+		for websocket.Message.Receive(ws, &msg) == nil {
+		}
+	}()
+
+	s.updates(ctx, ws.Request(), func(update Readings) error {
+		return websocket.JSON.Send(ws, update)
+	}, nil)
+}
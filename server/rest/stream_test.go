@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"golang.org/x/net/websocket"
+)
+
+// waitSubscribers waits until n streaming clients are connected
+func waitSubscribers(t *testing.T, s *Server, n int) {
+	t.Helper()
+
+	for range 100 {
+		s.mu.Lock()
+		got := len(s.subs)
+		s.mu.Unlock()
+
+		if got == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("subscribers did not reach %d", n)
+}
+
+// TestStreamEvents tests Server-Sent Events of published readings
+func TestStreamEvents(t *testing.T) {
+	s := New(nil, nil)
+	s.Publish("pv", []emhcasa.Reading{{OBIS: "2.8.0", Value: 10}}, nil)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/api/v1/stream?gateway=home&obis=16.7.0")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	waitSubscribers(t, s, 1)
+	s.Publish("home", []emhcasa.Reading{{OBIS: "1.8.0", Value: 100}, {OBIS: "16.7.0", Value: 500}}, nil)
+	s.Publish("home", nil, errors.New("gateway unavailable"))
+
+	sc := bufio.NewScanner(resp.Body)
+	for _, want := range []Readings{
+		{Gateway: "home", Readings: []emhcasa.Reading{{OBIS: "16.7.0", Value: 500}}},
+		{Gateway: "home", Readings: []emhcasa.Reading{}, Error: "gateway unavailable"},
+	} {
+		var lines []string
+		for sc.Scan() && sc.Text() != "" {
+			lines = append(lines, sc.Text())
+		}
+
+		if len(lines) != 2 || lines[0] != "event: readings" || !strings.HasPrefix(lines[1], "data: ") {
+			t.Fatalf("event = %q", lines)
+		}
+
+		var got Readings
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if got.Gateway != want.Gateway || got.Error != want.Error || len(got.Readings) != len(want.Readings) ||
+			len(got.Readings) > 0 && got.Readings[0].Value != want.Readings[0].Value {
+			t.Errorf("event = %+v, want %+v", got, want)
+		}
+	}
+}
+
+// TestStreamWebSocket tests WebSocket messages including the latest update on connect
+func TestStreamWebSocket(t *testing.T) {
+	s := New(nil, nil)
+	s.Publish("home", []emhcasa.Reading{{OBIS: "16.7.0", Value: 500}}, nil)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/v1/ws", "", srv.URL)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer ws.Close()
+
+	var got Readings
+	if err := websocket.JSON.Receive(ws, &got); err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if got.Gateway != "home" || len(got.Readings) != 1 || got.Readings[0].Value != 500 {
+		t.Errorf("initial message = %+v", got)
+	}
+
+	s.Publish("home", []emhcasa.Reading{{OBIS: "16.7.0", Value: 700}}, nil)
+
+	if err := websocket.JSON.Receive(ws, &got); err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if len(got.Readings) != 1 || got.Readings[0].Value != 700 {
+		t.Errorf("update message = %+v", got)
+	}
+
+	ws.Close()
+	waitSubscribers(t, s, 0)
+}
@@ -0,0 +1,61 @@
+// Package server contains the HTTP plumbing shared by the built-in servers
+// (server/rest, exporter/prometheus): graceful shutdown, authentication and
+// TLS certificates.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ShutdownTimeout is the time in-flight requests are given to complete
+// after the context passed to ListenAndServe is cancelled.
+var ShutdownTimeout = 5 * time.Second
+
+// ListenAndServe serves h on addr until ctx is cancelled, then shuts the
+// server down gracefully and returns ctx.Err(). Request contexts are derived
+// from ctx, so long-running streaming requests end on cancellation as well.
+func ListenAndServe(ctx context.Context, addr string, h http.Handler) error {
+	return ListenAndServeTLS(ctx, addr, h, nil)
+}
+
+// ListenAndServeTLS is like ListenAndServe but serves HTTPS with the
+// certificates of config, e.g. from LoadOrCreateCertificate. If config is
+// nil, it serves plain HTTP.
+func ListenAndServeTLS(ctx context.Context, addr string, h http.Handler, config *tls.Config) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		TLSConfig:         config,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		if config != nil {
+			errc <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestListenAndServe tests graceful shutdown on cancellation and listen errors
+func TestListenAndServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- ListenAndServe(ctx, "127.0.0.1:0", http.NotFoundHandler()) }()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ListenAndServe() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ListenAndServe() did not return after cancellation")
+	}
+
+	if err := ListenAndServe(context.Background(), "127.0.0.1:-1", http.NotFoundHandler()); err == nil {
+		t.Error("ListenAndServe() with invalid address succeeded")
+	}
+}
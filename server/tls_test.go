@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLoadOrCreateCertificate tests generation, persistence and serving of
+// a self-signed certificate
+func TestLoadOrCreateCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	cert, err := LoadOrCreateCertificate(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("LoadOrCreateCertificate() error = %v", err)
+	}
+	if fi, err := os.Stat(keyFile); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("key file = %v, %v", fi, err)
+	}
+
+	again, err := LoadOrCreateCertificate(certFile, keyFile)
+	if err != nil || !bytes.Equal(again.Certificate[0], cert.Certificate[0]) {
+		t.Fatalf("LoadOrCreateCertificate() did not load the persisted certificate: %v", err)
+	}
+
+	if _, err := LoadOrCreateCertificate(certFile, filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("LoadOrCreateCertificate() with missing key succeeded")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_, port, _ := net.SplitHostPort(addr)
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	go func() { _ = ListenAndServeTLS(ctx, addr, ok, &tls.Config{Certificates: []tls.Certificate{cert}}) }()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	for range 50 {
+		var resp *http.Response
+		if resp, err = client.Get("https://localhost:" + port); err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+}
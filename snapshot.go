@@ -0,0 +1,125 @@
+package emhcasa
+
+import "github.com/iseeberg79/emh-casa-go/obis"
+
+// GridSnapshot is a direction-normalized view of the grid connection point.
+// Power and energy values are always non-negative; the direction is encoded
+// by the field they are stored in.
+type GridSnapshot struct {
+	ImportW   float64    // current power drawn from the grid (W)
+	ExportW   float64    // current power fed into the grid (W)
+	ImportkWh float64    // total imported energy (kWh)
+	ExportkWh float64    // total exported energy (kWh)
+	PerPhase  [3]float64 // signed active power per phase L1-L3 (W), positive = import
+}
+
+// NewGridSnapshot builds a GridSnapshot from values returned by GetMeterValues
+// using the default PowerConfig.
+//
+// Gateways encode the power direction differently: some report separate
+// import/export registers (1.7.0/2.7.0), others a signed 16.7.0 value that is
+// negative during feed-in. Separate registers take precedence if present.
+func NewGridSnapshot(values map[string]float64) GridSnapshot {
+	return PowerConfig{}.Snapshot(values)
+}
+
+// PowerMode selects the registers the grid power direction is derived from.
+type PowerMode int
+
+const (
+	PowerAuto     PowerMode = iota // import/export registers if present, else signed 16.7.0
+	PowerSigned                    // signed 16.7.0 only
+	PowerSeparate                  // import/export registers 1.7.0/2.7.0 only
+)
+
+// PowerConfig configures the power normalization of an installation.
+// The zero value uses PowerAuto without inversion.
+type PowerConfig struct {
+	Mode   PowerMode
+	Invert bool // meter counts in reverse, e.g. installed with swapped direction
+}
+
+// Normalize returns the signed active power at the grid connection point
+// (W, positive = import) and its direction.
+func (c PowerConfig) Normalize(values map[string]float64) (float64, obis.Direction) {
+	imp, hasImport := values[obis.PowerImport]
+	exp, hasExport := values[obis.PowerExport]
+
+	power := values[obis.Power]
+	if c.Mode == PowerSeparate || c.Mode == PowerAuto && (hasImport || hasExport) {
+		power = imp - exp
+	}
+
+	if c.Invert {
+		power = -power
+	}
+
+	switch {
+	case power > 0:
+		return power, obis.DirectionImport
+	case power < 0:
+		return power, obis.DirectionExport
+	}
+	return 0, obis.DirectionNone
+}
+
+// Snapshot builds a GridSnapshot from values returned by GetMeterValues.
+// With Invert, energy registers and per-phase power are reversed as well.
+func (c PowerConfig) Snapshot(values map[string]float64) GridSnapshot {
+	s := GridSnapshot{
+		ImportkWh: values[obis.EnergyImport],
+		ExportkWh: values[obis.EnergyExport],
+	}
+
+	if c.Invert {
+		s.ImportkWh, s.ExportkWh = s.ExportkWh, s.ImportkWh
+	}
+
+	switch power, dir := c.Normalize(values); dir {
+	case obis.DirectionImport:
+		s.ImportW = power
+	case obis.DirectionExport:
+		s.ExportW = -power
+	}
+
+	for i, p := range Phases(values) {
+		s.PerPhase[i] = p.PowerW
+		if c.Invert {
+			s.PerPhase[i] = -p.PowerW
+		}
+	}
+
+	return s
+}
+
+// PhaseReading holds the values of a single phase.
+type PhaseReading struct {
+	VoltageV float64 // voltage (V)
+	CurrentA float64 // current (A)
+	PowerW   float64 // signed active power (W), positive = import
+}
+
+// Phases resolves the values of phases L1-L3 from values returned by GetMeterValues.
+// Power is taken from the signed per-phase register or, if missing, from the
+// separate import/export registers.
+func Phases(values map[string]float64) [3]PhaseReading {
+	codes := [3]struct{ voltage, current, signed, imp, exp string }{
+		{obis.VoltageL1, obis.CurrentL1, obis.PowerL1, obis.PowerImportL1, obis.PowerExportL1},
+		{obis.VoltageL2, obis.CurrentL2, obis.PowerL2, obis.PowerImportL2, obis.PowerExportL2},
+		{obis.VoltageL3, obis.CurrentL3, obis.PowerL3, obis.PowerImportL3, obis.PowerExportL3},
+	}
+
+	var res [3]PhaseReading
+	for i, c := range codes {
+		res[i] = PhaseReading{
+			VoltageV: values[c.voltage],
+			CurrentA: values[c.current],
+			PowerW:   values[c.imp] - values[c.exp],
+		}
+		if v, ok := values[c.signed]; ok {
+			res[i].PowerW = v
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,115 @@
+package emhcasa
+
+import (
+	"testing"
+
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// TestNewGridSnapshot tests direction normalization of grid values
+func TestNewGridSnapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]float64
+		want   GridSnapshot
+	}{
+		{
+			name:   "signed power importing",
+			values: map[string]float64{"16.7.0": 1500, "1.8.0": 100, "2.8.0": 20},
+			want:   GridSnapshot{ImportW: 1500, ImportkWh: 100, ExportkWh: 20},
+		},
+		{
+			name:   "signed power exporting",
+			values: map[string]float64{"16.7.0": -800},
+			want:   GridSnapshot{ExportW: 800},
+		},
+		{
+			name:   "separate registers take precedence",
+			values: map[string]float64{"16.7.0": 700, "1.7.0": 0, "2.7.0": 300},
+			want:   GridSnapshot{ExportW: 300},
+		},
+		{
+			name: "per phase signed power",
+			values: map[string]float64{
+				"36.7.0": 100, "56.7.0": -200, "76.7.0": 300,
+			},
+			want: GridSnapshot{PerPhase: [3]float64{100, -200, 300}},
+		},
+		{
+			name: "per phase import/export pairs",
+			values: map[string]float64{
+				"21.7.0": 100, "22.7.0": 0,
+				"41.7.0": 0, "42.7.0": 250,
+				"61.7.0": 50, "62.7.0": 0,
+			},
+			want: GridSnapshot{PerPhase: [3]float64{100, -250, 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewGridSnapshot(tt.values)
+			if got != tt.want {
+				t.Errorf("NewGridSnapshot() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPhases tests resolving per-phase voltage, current and power
+func TestPhases(t *testing.T) {
+	values := map[string]float64{
+		"32.7.0": 230, "31.7.0": 2, "36.7.0": 460,
+		"52.7.0": 231, "51.7.0": 1, "41.7.0": 0, "42.7.0": 231,
+		"72.7.0": 229,
+	}
+
+	want := [3]PhaseReading{
+		{VoltageV: 230, CurrentA: 2, PowerW: 460},
+		{VoltageV: 231, CurrentA: 1, PowerW: -231},
+		{VoltageV: 229},
+	}
+
+	if got := Phases(values); got != want {
+		t.Errorf("Phases() = %+v, want %+v", got, want)
+	}
+}
+
+// TestPowerConfigNormalize tests configurable power normalization
+func TestPowerConfigNormalize(t *testing.T) {
+	values := map[string]float64{"16.7.0": -500, "1.7.0": 200, "2.7.0": 0}
+
+	tests := []struct {
+		name    string
+		config  PowerConfig
+		values  map[string]float64
+		want    float64
+		wantDir obis.Direction
+	}{
+		{"auto prefers separate registers", PowerConfig{}, values, 200, obis.DirectionImport},
+		{"auto falls back to signed", PowerConfig{}, map[string]float64{"16.7.0": -500}, -500, obis.DirectionExport},
+		{"signed only", PowerConfig{Mode: PowerSigned}, values, -500, obis.DirectionExport},
+		{"separate only", PowerConfig{Mode: PowerSeparate}, map[string]float64{"16.7.0": -500}, 0, obis.DirectionNone},
+		{"inverted", PowerConfig{Mode: PowerSigned, Invert: true}, values, 500, obis.DirectionImport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dir := tt.config.Normalize(tt.values)
+			if got != tt.want || dir != tt.wantDir {
+				t.Errorf("Normalize() = %v, %v, want %v, %v", got, dir, tt.want, tt.wantDir)
+			}
+		})
+	}
+}
+
+// TestPowerConfigSnapshotInvert tests that inversion reverses energies and phases
+func TestPowerConfigSnapshotInvert(t *testing.T) {
+	values := map[string]float64{"16.7.0": 800, "1.8.0": 100, "2.8.0": 20, "36.7.0": 300}
+
+	got := PowerConfig{Invert: true}.Snapshot(values)
+	want := GridSnapshot{ExportW: 800, ImportkWh: 20, ExportkWh: 100, PerPhase: [3]float64{-300, 0, 0}}
+	if got != want {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Window aggregates the readings of an OBIS code within a time window.
+type Window struct {
+	Start time.Time `json:"start"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Avg   float64   `json:"avg"`
+	Count int       `json:"count"`
+	Gap   bool      `json:"gap,omitempty"` // no readings, inserted by FillGaps
+}
+
+// Aggregate downsamples the readings of an OBIS code in [from, to) into windows
+// of the given size, e.g. time.Minute, 15*time.Minute or time.Hour. Windows are
+// aligned to multiples of size since the zero time (UTC); windows without
+// readings are omitted.
+func (s *Store) Aggregate(code string, from, to time.Time, size time.Duration) ([]Window, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid window size: %v", size)
+	}
+
+	readings, err := s.Query(code, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Window
+	index := make(map[time.Time]int) // window start to index in res
+
+	for _, r := range readings {
+		start := r.Timestamp.Truncate(size).UTC()
+
+		i, ok := index[start]
+		if !ok {
+			i = len(res)
+			index[start] = i
+			res = append(res, Window{Start: start, Min: r.Value, Max: r.Value})
+		}
+
+		w := &res[i]
+		w.Min, w.Max = min(w.Min, r.Value), max(w.Max, r.Value)
+		w.Avg += (r.Value - w.Avg) / float64(w.Count+1)
+		w.Count++
+	}
+
+	slices.SortFunc(res, func(a, b Window) int { return a.Start.Compare(b.Start) })
+
+	return res, nil
+}
+
+// FillGaps returns windows for the complete range [from, to), inserting windows
+// marked as Gap where windows has none. With interpolate, gap values are
+// interpolated linearly between the averages of the surrounding windows; gaps
+// at the start or end of the range stay zero.
+func FillGaps(windows []Window, from, to time.Time, size time.Duration, interpolate bool) []Window {
+	if size <= 0 {
+		return windows
+	}
+
+	var (
+		res  []Window
+		prev *Window // last window with data
+	)
+	next := 0 // index in windows
+
+	for start := from.Truncate(size).UTC(); start.Before(to); start = start.Add(size) {
+		for next < len(windows) && windows[next].Start.Before(start) {
+			next++
+		}
+
+		if next < len(windows) && windows[next].Start.Equal(start) {
+			prev = &windows[next]
+			res = append(res, windows[next])
+			next++
+			continue
+		}
+
+		w := Window{Start: start, Gap: true}
+		if interpolate && prev != nil && next < len(windows) {
+			after := windows[next]
+			f := float64(start.Sub(prev.Start)) / float64(after.Start.Sub(prev.Start))
+			w.Avg = prev.Avg + f*(after.Avg-prev.Avg)
+			w.Min, w.Max = w.Avg, w.Avg
+		}
+		res = append(res, w)
+	}
+
+	return res
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// TestAggregate tests downsampling into min/max/avg windows
+func TestAggregate(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "readings.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var readings []emhcasa.Reading
+	for i, v := range []float64{100, 300, 200, 1000, 500} {
+		readings = append(readings, emhcasa.Reading{OBIS: "16.7.0", Value: v, Timestamp: base.Add(time.Duration(i) * 5 * time.Minute)})
+	}
+	// late reading for the first window
+	readings = append(readings, emhcasa.Reading{OBIS: "16.7.0", Value: 400, Timestamp: base.Add(time.Minute)})
+
+	if err := s.Add(readings); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, err := s.Aggregate("16.7.0", base, base.Add(time.Hour), 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Aggregate() error = %v", err)
+	}
+
+	want := []Window{
+		{Start: base, Min: 100, Max: 400, Avg: 250, Count: 4},
+		{Start: base.Add(15 * time.Minute), Min: 500, Max: 1000, Avg: 750, Count: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Aggregate() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Aggregate()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if _, err := s.Aggregate("16.7.0", base, base.Add(time.Hour), 0); err == nil {
+		t.Error("Aggregate() expected error for zero window size")
+	}
+}
+
+// TestFillGaps tests gap marking and interpolation of missing windows
+func TestFillGaps(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(i int) time.Time { return base.Add(time.Duration(i) * 15 * time.Minute) }
+
+	windows := []Window{
+		{Start: at(1), Min: 90, Max: 110, Avg: 100, Count: 3},
+		{Start: at(4), Min: 400, Max: 400, Avg: 400, Count: 1},
+	}
+
+	tests := []struct {
+		name        string
+		windows     []Window
+		interpolate bool
+		to          time.Time
+		want        []Window
+	}{
+		{
+			name: "mark only",
+			want: []Window{
+				{Start: at(0), Gap: true},
+				windows[0],
+				{Start: at(2), Gap: true},
+				{Start: at(3), Gap: true},
+				windows[1],
+				{Start: at(5), Gap: true},
+			},
+		},
+		{
+			name:        "interpolate",
+			interpolate: true,
+			want: []Window{
+				{Start: at(0), Gap: true},
+				windows[0],
+				{Start: at(2), Min: 200, Max: 200, Avg: 200, Gap: true},
+				{Start: at(3), Min: 300, Max: 300, Avg: 300, Gap: true},
+				windows[1],
+				{Start: at(5), Gap: true},
+			},
+		},
+		{
+			name:        "leading gaps",
+			windows:     []Window{{Start: at(2), Min: 10, Max: 10, Avg: 10, Count: 1}},
+			interpolate: true,
+			to:          at(4),
+			want: []Window{
+				{Start: at(0), Gap: true},
+				{Start: at(1), Gap: true},
+				{Start: at(2), Min: 10, Max: 10, Avg: 10, Count: 1},
+				{Start: at(3), Gap: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, to := windows, at(6)
+			if tt.windows != nil {
+				in, to = tt.windows, tt.to
+			}
+
+			got := FillGaps(in, at(0), to, 15*time.Minute, tt.interpolate)
+			if len(got) != len(tt.want) {
+				t.Fatalf("FillGaps() = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("FillGaps()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
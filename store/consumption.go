@@ -0,0 +1,149 @@
+package store
+
+import (
+	"slices"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/obis"
+)
+
+// Interval is the length of a consumption period.
+type Interval int
+
+const (
+	Daily Interval = iota
+	Monthly
+)
+
+// Period is the energy imported and exported within [Start, End).
+type Period struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	ImportkWh float64   `json:"import_kwh"`
+	ExportkWh float64   `json:"export_kwh"`
+}
+
+// Consumption computes the imported and exported energy per day or month in
+// [from, to) from the stored energy counters (1.8.0, 2.8.0). Period boundaries
+// are calendar days or months in the location of from.
+//
+// The counter increase between two readings is split across the periods they
+// span in proportion to time, so gaps in the history (e.g. while the poller was
+// down) don't attribute the whole consumption to a single period. Counter
+// decreases, e.g. after a meter swap, are skipped.
+func (s *Store) Consumption(from, to time.Time, interval Interval) ([]Period, error) {
+	return s.consumption(from, to, interval, "")
+}
+
+// consumption implements Consumption for the counters of the given source
+// prefix, e.g. "grid/" for readings stored from an Aggregate
+func (s *Store) consumption(from, to time.Time, interval Interval, prefix string) ([]Period, error) {
+	var periods []Period
+	for start := periodStart(from, interval); start.Before(to); {
+		end := start.AddDate(0, 0, 1)
+		if interval == Monthly {
+			end = start.AddDate(0, 1, 0)
+		}
+		periods = append(periods, Period{Start: start, End: end})
+		start = end
+	}
+
+	err := s.counter(prefix+obis.EnergyImport, from, to, periods, func(i int, kWh float64) { periods[i].ImportkWh += kWh })
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.counter(prefix+obis.EnergyExport, from, to, periods, func(i int, kWh float64) { periods[i].ExportkWh += kWh })
+	if err != nil {
+		return nil, err
+	}
+
+	return periods, nil
+}
+
+// counter distributes the increases of the counter code to the periods, calling
+// add with the period index and energy
+func (s *Store) counter(code string, from, to time.Time, periods []Period, add func(int, float64)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// include readings before from and the first reading at or after to, the
+	// counter deltas to them reach into the range
+	var (
+		readings []emhcasa.Reading
+		next     *emhcasa.Reading
+	)
+	err := s.scan(func(_ []byte, r emhcasa.Reading) error {
+		switch {
+		case r.OBIS != code:
+		case r.Timestamp.Before(to):
+			readings = append(readings, r)
+		case next == nil || r.Timestamp.Before(next.Timestamp):
+			next = &r
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if next != nil {
+		readings = append(readings, *next)
+	}
+
+	distribute(periods, from, to, readings, add)
+
+	return nil
+}
+
+// periodStart returns the start of the day or month containing t
+func periodStart(t time.Time, interval Interval) time.Time {
+	if interval == Monthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// distribute adds the counter increases between consecutive readings to the
+// overlapping periods, limited to [from, to)
+func distribute(periods []Period, from, to time.Time, readings []emhcasa.Reading, add func(int, float64)) {
+	slices.SortFunc(readings, func(a, b emhcasa.Reading) int { return a.Timestamp.Compare(b.Timestamp) })
+
+	for i := 1; i < len(readings); i++ {
+		a, b := readings[i-1], readings[i]
+
+		delta, span := b.Value-a.Value, b.Timestamp.Sub(a.Timestamp)
+		if delta < 0 || span <= 0 {
+			continue
+		}
+
+		for j := range periods {
+			start := latest(periods[j].Start, from, a.Timestamp)
+			end := earliest(periods[j].End, to, b.Timestamp)
+			if overlap := end.Sub(start); overlap > 0 {
+				add(j, delta*float64(overlap)/float64(span))
+			}
+		}
+	}
+}
+
+// latest returns the latest of the given times
+func latest(t time.Time, ts ...time.Time) time.Time {
+	for _, o := range ts {
+		if o.After(t) {
+			t = o
+		}
+	}
+	return t
+}
+
+// earliest returns the earliest of the given times
+func earliest(t time.Time, ts ...time.Time) time.Time {
+	for _, o := range ts {
+		if o.Before(t) {
+			t = o
+		}
+	}
+	return t
+}
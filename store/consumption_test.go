@@ -0,0 +1,115 @@
+package store
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// TestConsumption tests daily consumption including gaps and counter resets
+func TestConsumption(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "readings.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+
+	err = s.Add([]emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 100, Timestamp: at(-12)},
+		{OBIS: "1.8.0", Value: 106, Timestamp: at(12)}, // 6 kWh over 24 h: 3 before, 3 on day 1
+		{OBIS: "1.8.0", Value: 110, Timestamp: at(18)},
+		{OBIS: "2.8.0", Value: 50, Timestamp: at(6)},
+		{OBIS: "2.8.0", Value: 54, Timestamp: at(30)}, // gap: 4 kWh over 24 h, split 18:6
+		{OBIS: "2.8.0", Value: 1, Timestamp: at(36)},  // meter swap
+		{OBIS: "2.8.0", Value: 3, Timestamp: at(42)},
+	})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, err := s.Consumption(day, day.AddDate(0, 0, 2), Daily)
+	if err != nil {
+		t.Fatalf("Consumption() error = %v", err)
+	}
+
+	want := []Period{
+		{Start: day, End: day.AddDate(0, 0, 1), ImportkWh: 7, ExportkWh: 3},
+		{Start: day.AddDate(0, 0, 1), End: day.AddDate(0, 0, 2), ImportkWh: 0, ExportkWh: 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Consumption() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if !g.Start.Equal(w.Start) || !g.End.Equal(w.End) ||
+			math.Abs(g.ImportkWh-w.ImportkWh) > 1e-9 || math.Abs(g.ExportkWh-w.ExportkWh) > 1e-9 {
+			t.Errorf("Consumption()[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+// TestConsumptionEnd tests that the delta to the first reading after the end
+// is included, so adjacent ranges add up
+func TestConsumptionEnd(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "readings.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+
+	err = s.Add([]emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 100, Timestamp: at(0)},
+		{OBIS: "1.8.0", Value: 106, Timestamp: at(12)},
+		{OBIS: "1.8.0", Value: 118, Timestamp: at(36)}, // 12 kWh over 24 h: 6 on each day
+		{OBIS: "1.8.0", Value: 130, Timestamp: at(60)},
+	})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	var sum float64
+	for i, want := range []float64{12, 12} {
+		got, err := s.Consumption(day.AddDate(0, 0, i), day.AddDate(0, 0, i+1), Daily)
+		if err != nil {
+			t.Fatalf("Consumption() error = %v", err)
+		}
+		if len(got) != 1 || math.Abs(got[0].ImportkWh-want) > 1e-9 {
+			t.Errorf("Consumption() of day %d = %+v, want %v kWh", i+1, got, want)
+		}
+		sum += got[0].ImportkWh
+	}
+
+	got, err := s.Consumption(day, day.AddDate(0, 0, 2), Daily)
+	if err != nil {
+		t.Fatalf("Consumption() error = %v", err)
+	}
+	if total := got[0].ImportkWh + got[1].ImportkWh; math.Abs(total-sum) > 1e-9 {
+		t.Errorf("Consumption() of both days = %v kWh, want %v", total, sum)
+	}
+}
+
+// TestConsumptionMonthly tests monthly period boundaries
+func TestConsumptionMonthly(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "readings.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+
+	got, err := s.Consumption(time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Monthly)
+	if err != nil {
+		t.Fatalf("Consumption() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Start.Month() != time.January || got[1].End.Month() != time.March {
+		t.Errorf("Consumption() = %+v, want January and February", got)
+	}
+}
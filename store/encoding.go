@@ -0,0 +1,75 @@
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"github.com/iseeberg79/emh-casa-go/internal/protowire"
+)
+
+// Series can be sent as interface values, e.g. with net/rpc.
+func init() {
+	gob.Register(Series{})
+}
+
+// MarshalBinary encodes the series as protobuf message emhcasa.v1.Series
+// (see proto/emhcasa.proto). It is also used by encoding/gob.
+func (s Series) MarshalBinary() ([]byte, error) {
+	var b []byte
+	if s.OBIS != "" {
+		b = protowire.AppendString(b, 1, s.OBIS)
+	}
+	if s.Unit != "" {
+		b = protowire.AppendString(b, 2, s.Unit)
+	}
+
+	for _, v := range s.Values {
+		var tv []byte
+		if !v.Time.IsZero() {
+			tv = protowire.AppendVarint(tv, 1, uint64(v.Time.UnixNano()))
+		}
+		if v.Value != 0 {
+			tv = protowire.AppendDouble(tv, 2, v.Value)
+		}
+		b = protowire.AppendBytes(b, 3, tv)
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary decodes a series encoded by MarshalBinary. Times are
+// returned in the local time zone.
+func (s *Series) UnmarshalBinary(b []byte) error {
+	*s = Series{}
+
+	err := protowire.Range(b, func(f protowire.Field) error {
+		switch {
+		case f.Is(1, protowire.Bytes):
+			s.OBIS = string(f.Bytes)
+		case f.Is(2, protowire.Bytes):
+			s.Unit = string(f.Bytes)
+		case f.Is(3, protowire.Bytes):
+			var v TimedValue
+			err := protowire.Range(f.Bytes, func(f protowire.Field) error {
+				switch {
+				case f.Is(1, protowire.Varint):
+					v.Time = time.Unix(0, f.Int())
+				case f.Is(2, protowire.Fixed64):
+					v.Value = f.Double()
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			s.Values = append(s.Values, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("series: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package store
+
+import "time"
+
+// SelfConsumption are the PV statistics of a period.
+type SelfConsumption struct {
+	Period
+	GenerationkWh        float64 `json:"generation_kwh"`
+	SelfConsumedkWh      float64 `json:"self_consumed_kwh"` // generation consumed on site
+	SelfConsumptionRatio float64 `json:"self_consumption"`  // share of generation consumed on site, 0-1
+	Autarky              float64 `json:"autarky"`           // share of consumption covered by generation, 0-1
+}
+
+// SelfConsumption computes self-consumption and autarky per day or month from
+// the counters (1.8.0, 2.8.0) of the grid meter and the stored generation
+// counter of a PV meter. For readings stored from an Aggregate, grid is the
+// source name of the grid meter, e.g. "grid", and generation e.g. "pv/2.8.0",
+// as the plain keys hold the sums across sources, which include the
+// generation. Otherwise grid is empty. Without generation counter only the
+// feed-in (ExportkWh) is known and the ratios are zero.
+func (s *Store) SelfConsumption(from, to time.Time, interval Interval, grid, generation string) ([]SelfConsumption, error) {
+	var prefix string
+	if grid != "" {
+		prefix = grid + "/"
+	}
+
+	periods, err := s.consumption(from, to, interval, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := make([]float64, len(periods))
+	if generation != "" {
+		if err := s.counter(generation, from, to, periods, func(i int, kWh float64) { gen[i] += kWh }); err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]SelfConsumption, len(periods))
+	for i, p := range periods {
+		sc := SelfConsumption{Period: p}
+
+		if generation != "" {
+			sc.GenerationkWh = gen[i]
+			sc.SelfConsumedkWh = max(0, sc.GenerationkWh-p.ExportkWh)
+
+			if sc.GenerationkWh > 0 {
+				sc.SelfConsumptionRatio = sc.SelfConsumedkWh / sc.GenerationkWh
+			}
+			if consumption := p.ImportkWh + sc.SelfConsumedkWh; consumption > 0 {
+				sc.Autarky = sc.SelfConsumedkWh / consumption
+			}
+		}
+
+		res[i] = sc
+	}
+
+	return res, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+	"github.com/iseeberg79/emh-casa-go/mock"
+)
+
+// TestSelfConsumption tests self-consumption and autarky per day
+func TestSelfConsumption(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "readings.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+
+	day := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(0, 0, 1)
+
+	err = s.Add([]emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 1000, Timestamp: day},
+		{OBIS: "1.8.0", Value: 1005, Timestamp: end},
+		{OBIS: "2.8.0", Value: 500, Timestamp: day},
+		{OBIS: "2.8.0", Value: 512, Timestamp: end},
+		{OBIS: "pv/2.8.0", Value: 2000, Timestamp: day},
+		{OBIS: "pv/2.8.0", Value: 2020, Timestamp: end},
+	})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, err := s.SelfConsumption(day, end, Daily, "", "pv/2.8.0")
+	if err != nil {
+		t.Fatalf("SelfConsumption() error = %v", err)
+	}
+
+	// 20 kWh generated, 12 fed in → 8 self-consumed of 13 consumed
+	want := SelfConsumption{
+		Period:               Period{Start: day, End: end, ImportkWh: 5, ExportkWh: 12},
+		GenerationkWh:        20,
+		SelfConsumedkWh:      8,
+		SelfConsumptionRatio: 0.4,
+		Autarky:              8.0 / 13,
+	}
+	if len(got) != 1 {
+		t.Fatalf("SelfConsumption() = %+v, want [%+v]", got, want)
+	}
+	g := got[0]
+	for _, v := range [][2]float64{
+		{g.ImportkWh, want.ImportkWh}, {g.ExportkWh, want.ExportkWh},
+		{g.GenerationkWh, want.GenerationkWh}, {g.SelfConsumedkWh, want.SelfConsumedkWh},
+		{g.SelfConsumptionRatio, want.SelfConsumptionRatio}, {g.Autarky, want.Autarky},
+	} {
+		if math.Abs(v[0]-v[1]) > 1e-9 {
+			t.Errorf("SelfConsumption() = %+v, want %+v", g, want)
+			break
+		}
+	}
+
+	got, err = s.SelfConsumption(day, end, Daily, "", "")
+	if err != nil {
+		t.Fatalf("SelfConsumption() error = %v", err)
+	}
+	if got[0].ExportkWh != 12 || got[0].Autarky != 0 {
+		t.Errorf("SelfConsumption() without generation = %+v, want feed-in only", got[0])
+	}
+}
+
+// TestSelfConsumptionAggregate tests readings stored from an Aggregate, whose
+// plain keys sum grid and PV meter
+func TestSelfConsumptionAggregate(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "readings.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+
+	day := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(0, 0, 1)
+
+	agg := emhcasa.NewAggregate(map[string]emhcasa.Gateway{
+		"grid": mock.New(
+			mock.Response{Readings: mock.Readings(map[string]float64{"1.8.0": 1000, "2.8.0": 500})},
+			mock.Response{Readings: mock.Readings(map[string]float64{"1.8.0": 1005, "2.8.0": 512})},
+		),
+		"pv": mock.New(
+			mock.Response{Readings: mock.Readings(map[string]float64{"1.8.0": 10, "2.8.0": 2000})},
+			mock.Response{Readings: mock.Readings(map[string]float64{"1.8.0": 10.5, "2.8.0": 2020})},
+		),
+	})
+
+	for _, ts := range []time.Time{day, end} {
+		readings, err := agg.GetReadings()
+		if err != nil {
+			t.Fatalf("GetReadings() error = %v", err)
+		}
+		for i := range readings {
+			readings[i].Timestamp = ts
+		}
+		if err := s.Add(readings); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	got, err := s.SelfConsumption(day, end, Daily, "grid", "pv/2.8.0")
+	if err != nil {
+		t.Fatalf("SelfConsumption() error = %v", err)
+	}
+
+	// 20 kWh generated, 12 fed in → 8 self-consumed of 13 consumed
+	if len(got) != 1 {
+		t.Fatalf("SelfConsumption() = %+v, want 1 period", got)
+	}
+	g := got[0]
+	for _, v := range [][2]float64{
+		{g.ImportkWh, 5}, {g.ExportkWh, 12}, {g.GenerationkWh, 20},
+		{g.SelfConsumedkWh, 8}, {g.SelfConsumptionRatio, 0.4}, {g.Autarky, 8.0 / 13},
+	} {
+		if math.Abs(v[0]-v[1]) > 1e-9 {
+			t.Errorf("SelfConsumption() = %+v, want 8 kWh self-consumed of 20 generated", g)
+			break
+		}
+	}
+}
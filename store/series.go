@@ -0,0 +1,101 @@
+package store
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// TimedValue is a value at a point in time.
+type TimedValue struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Series is a time series of an OBIS register, ordered by time.
+type Series struct {
+	OBIS   string       `json:"obis"`
+	Unit   string       `json:"unit"` // unit of the values, e.g. "kWh"
+	Values []TimedValue `json:"values"`
+}
+
+// Series returns the stored values of an OBIS code in [from, to) ordered by time.
+func (s *Store) Series(code string, from, to time.Time) (Series, error) {
+	readings, err := s.Query(code, from, to)
+	if err != nil {
+		return Series{}, err
+	}
+
+	res := Series{OBIS: code}
+	for _, r := range readings {
+		res.Unit = r.ValueUnit()
+		res.Values = append(res.Values, TimedValue{Time: r.Timestamp, Value: r.Value})
+	}
+	res.sort()
+
+	return res, nil
+}
+
+// Resample averages the values within aligned windows of the given size,
+// stamped with the window start. Windows without values are omitted.
+func (s Series) Resample(size time.Duration) Series {
+	res := Series{OBIS: s.OBIS, Unit: s.Unit}
+	if size <= 0 {
+		return res
+	}
+
+	var n int
+	for _, v := range s.Values {
+		start := v.Time.Truncate(size)
+
+		if len(res.Values) == 0 || !res.Values[len(res.Values)-1].Time.Equal(start) {
+			res.Values = append(res.Values, TimedValue{Time: start})
+			n = 0
+		}
+
+		last := &res.Values[len(res.Values)-1]
+		last.Value += (v.Value - last.Value) / float64(n+1)
+		n++
+	}
+
+	return res
+}
+
+// Deltas converts a counter series into the increases between consecutive
+// values, stamped with the time of the later value. Decreases, e.g. after a
+// counter reset, are omitted.
+func (s Series) Deltas() Series {
+	res := Series{OBIS: s.OBIS, Unit: s.Unit}
+
+	for i := 1; i < len(s.Values); i++ {
+		if d := s.Values[i].Value - s.Values[i-1].Value; d >= 0 {
+			res.Values = append(res.Values, TimedValue{Time: s.Values[i].Time, Value: d})
+		}
+	}
+
+	return res
+}
+
+// WriteCSV writes the series as CSV with a header row and RFC 3339 timestamps.
+func (s Series) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"time", s.OBIS + " (" + s.Unit + ")"}); err != nil {
+		return err
+	}
+	for _, v := range s.Values {
+		if err := cw.Write([]string{v.Time.Format(time.RFC3339), strconv.FormatFloat(v.Value, 'f', -1, 64)}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// sort orders the values by time, keeping insertion order for equal times
+func (s Series) sort() {
+	slices.SortStableFunc(s.Values, func(a, b TimedValue) int { return a.Time.Compare(b.Time) })
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// TestSeries tests series queries, resampling, deltas and formats
+func TestSeries(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "readings.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer st.Close()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return base.Add(time.Duration(m) * time.Minute) }
+
+	err = st.Add([]emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 102, Unit: emhcasa.UnitWattHour, Timestamp: at(10)},
+		{OBIS: "1.8.0", Value: 100, Unit: emhcasa.UnitWattHour, Timestamp: at(0)},
+		{OBIS: "1.8.0", Value: 101, Unit: emhcasa.UnitWattHour, Timestamp: at(5)},
+		{OBIS: "1.8.0", Value: 1, Unit: emhcasa.UnitWattHour, Timestamp: at(20)},
+	})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	s, err := st.Series("1.8.0", base, at(30))
+	if err != nil {
+		t.Fatalf("Series() error = %v", err)
+	}
+	if s.Unit != "kWh" || len(s.Values) != 4 || s.Values[0].Value != 100 || s.Values[3].Value != 1 {
+		t.Fatalf("Series() = %+v, want 4 time-ordered kWh values", s)
+	}
+
+	if got := s.Resample(15 * time.Minute).Values; len(got) != 2 || got[0].Value != 101 || !got[1].Time.Equal(at(15)) {
+		t.Errorf("Resample() = %+v, want averages 101 and 1", got)
+	}
+
+	if got := s.Deltas().Values; len(got) != 2 || got[0].Value != 1 || got[1].Value != 1 || !got[1].Time.Equal(at(10)) {
+		t.Errorf("Deltas() = %+v, want two increases of 1 without the reset", got)
+	}
+
+	var b strings.Builder
+	if err := s.Deltas().WriteCSV(&b); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := "time,1.8.0 (kWh)\n2026-03-01T12:05:00Z,1\n2026-03-01T12:10:00Z,1\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", b.String(), want)
+	}
+
+	j, err := json.Marshal(s.Resample(time.Hour))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"obis":"1.8.0","unit":"kWh","values":[{"time":"2026-03-01T12:00:00Z","value":76}]}`; string(j) != want {
+		t.Errorf("Marshal() = %s, want %s", j, want)
+	}
+}
+
+// TestSeriesBinary tests the protobuf and gob round trips of series
+func TestSeriesBinary(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	series := Series{OBIS: "1.8.0", Unit: "kWh", Values: []TimedValue{
+		{Time: ts, Value: 100},
+		{Time: ts.Add(time.Hour), Value: 0},
+	}}
+
+	b, err := series.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+
+	var got Series
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, series) {
+		t.Errorf("UnmarshalBinary() = %+v, want %+v", got, series)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(series); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got = Series{}
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil || !reflect.DeepEqual(got, series) {
+		t.Errorf("gob = %+v, %v", got, err)
+	}
+
+	if err := got.UnmarshalBinary(b[:len(b)-1]); err == nil {
+		t.Error("UnmarshalBinary() of truncated message succeeded")
+	}
+}
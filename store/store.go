@@ -0,0 +1,207 @@
+// Package store persists readings in a local append-only file, so history
+// survives restarts and can be queried for gateways without history APIs.
+//
+// Readings are stored as JSON lines, one reading per line, in the encoding of
+// emhcasa.Reading. Queries scan the file, which is fine for the data volume of
+// a single household; use Prune with a retention to keep the file small.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// ErrReadOnly is returned by Add and Prune of a store opened with OpenReadOnly.
+var ErrReadOnly = errors.New("store is read-only")
+
+// Store is a file-backed reading history. It is safe for concurrent use.
+type Store struct {
+	path      string
+	retention time.Duration
+	readOnly  bool
+
+	mu sync.Mutex
+	f  *os.File // opened for appending
+}
+
+// Open opens or creates the store file at path. Readings older than retention
+// are dropped on Open and Prune; zero keeps all readings.
+func Open(path string, retention time.Duration) (*Store, error) {
+	s := &Store{path: path, retention: retention}
+
+	if err := s.Prune(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// OpenReadOnly opens the existing store file at path for queries only. The
+// file is neither pruned nor rewritten, so it can be read while another
+// process, e.g. a poller, appends to it.
+func OpenReadOnly(path string) (*Store, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	return &Store{path: path, readOnly: true}, nil
+}
+
+// Add appends readings to the store. Use it as poll.Poller callback:
+//
+//	OnReadings: func(r []emhcasa.Reading) { _ = st.Add(r) }
+func (s *Store) Add(readings []emhcasa.Reading) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	w := bufio.NewWriter(s.f)
+	enc := json.NewEncoder(w)
+	for _, r := range readings {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode reading: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write readings: %w", err)
+	}
+
+	return nil
+}
+
+// Query returns the stored readings of an OBIS code with a timestamp in [from, to),
+// in the order they were added.
+func (s *Store) Query(code string, from, to time.Time) ([]emhcasa.Reading, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []emhcasa.Reading
+	err := s.scan(func(_ []byte, r emhcasa.Reading) error {
+		if r.OBIS == code && !r.Timestamp.Before(from) && r.Timestamp.Before(to) {
+			res = append(res, r)
+		}
+		return nil
+	})
+
+	return res, err
+}
+
+// Prune drops readings older than the retention and undecodable lines by rewriting the file.
+// Call it periodically, e.g. daily, for long-running processes.
+func (s *Store) Prune() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	if s.f != nil {
+		if err := s.f.Close(); err != nil {
+			return fmt.Errorf("failed to close store: %w", err)
+		}
+		s.f = nil
+	}
+
+	// rewriting also drops partially written lines
+	var cutoff time.Time
+	if s.retention > 0 {
+		cutoff = time.Now().Add(-s.retention)
+	}
+	if err := s.rewrite(cutoff); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	s.f = f
+
+	return nil
+}
+
+// Close closes the store file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+	return s.f.Close()
+}
+
+// rewrite replaces the store file with one containing only readings not before cutoff
+func (s *Store) rewrite(cutoff time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".store-*")
+	if err != nil {
+		return fmt.Errorf("failed to prune store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	err = s.scan(func(line []byte, r emhcasa.Reading) error {
+		if r.Timestamp.Before(cutoff) {
+			return nil
+		}
+		_, err := w.Write(append(line, '\n'))
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to prune store: %w", err)
+	}
+
+	return nil
+}
+
+// scan calls fn for each stored reading, skipping lines that can't be decoded
+// (e.g. a partial write after a crash)
+func (s *Store) scan(fn func(line []byte, r emhcasa.Reading) error) error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r emhcasa.Reading
+		if json.Unmarshal(sc.Bytes(), &r) != nil {
+			continue
+		}
+		if err := fn(sc.Bytes(), r); err != nil {
+			return err
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("failed to read store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	emhcasa "github.com/iseeberg79/emh-casa-go"
+)
+
+// TestStore tests persistence, queries and retention across reopening
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.jsonl")
+	now := time.Now().Truncate(time.Second)
+
+	s, err := Open(path, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	err = s.Add([]emhcasa.Reading{
+		{OBIS: "1.8.0", Value: 100, Unit: emhcasa.UnitWattHour, Timestamp: now.Add(-48 * time.Hour)},
+		{OBIS: "1.8.0", Value: 110, Unit: emhcasa.UnitWattHour, Timestamp: now.Add(-time.Hour)},
+		{OBIS: "16.7.0", Value: 500, Unit: emhcasa.UnitWatt, Timestamp: now.Add(-time.Hour)},
+		{OBIS: "1.8.0", Value: 111, Unit: emhcasa.UnitWattHour, Timestamp: now},
+	})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, err := s.Query("1.8.0", now.Add(-72*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Value != 100 || got[1].Value != 110 {
+		t.Errorf("Query() = %+v, want values 100 and 110", got)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// simulate a partial write before a crash
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	_, _ = f.WriteString(`{"obis":"1.8`)
+	f.Close()
+
+	s, err = Open(path, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s.Close()
+
+	got, err = s.Query("1.8.0", now.Add(-72*time.Hour), now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Value != 110 || got[1].Value != 111 {
+		t.Errorf("Query() after reopen = %+v, want values 110 and 111", got)
+	}
+	if !got[1].Timestamp.Equal(now) || got[1].Unit != emhcasa.UnitWattHour {
+		t.Errorf("Query() reading = %+v, want timestamp %v and unit Wh", got[1], now)
+	}
+}
+
+// TestOpenReadOnly tests queries of a store file in use by another store
+func TestOpenReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.jsonl")
+	now := time.Now().Truncate(time.Second)
+
+	if _, err := OpenReadOnly(path); err == nil {
+		t.Error("OpenReadOnly() expected error for missing file")
+	}
+
+	w, err := Open(path, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer w.Close()
+
+	r, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenReadOnly() error = %v", err)
+	}
+	defer r.Close()
+
+	// readings added after opening are visible
+	if err := w.Add([]emhcasa.Reading{{OBIS: "1.8.0", Value: 100, Timestamp: now}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if got, err := r.Query("1.8.0", now, now.Add(time.Second)); err != nil || len(got) != 1 {
+		t.Errorf("Query() = %+v, %v, want one reading", got, err)
+	}
+
+	if err := r.Add(nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Add() error = %v, want ErrReadOnly", err)
+	}
+	if err := r.Prune(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Prune() error = %v, want ErrReadOnly", err)
+	}
+}
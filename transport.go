@@ -1,7 +1,10 @@
 package emhcasa
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"sync"
 
 	"github.com/jpfielding/go-http-digest/pkg/digest"
 )
@@ -27,6 +30,124 @@ func (t *hostHeaderTransport) RoundTrip(req *http.Request) (*http.Response, erro
 
 // NewDigestTransport creates an HTTP digest authentication transport.
 // It wraps the base RoundTripper with digest authentication credentials.
+// For requests without body, the server challenge is cached and reused until
+// the server rejects it, saving the 401 round-trip on every request.
 func NewDigestTransport(user, password string, base http.RoundTripper) http.RoundTripper {
-	return digest.NewTransport(user, password, base)
+	return &digestSessionTransport{auth: digest.NewTransport(user, password, base)}
+}
+
+// digestSessionTransport implements HTTP digest authentication, reusing the last
+// server challenge with an incremented nonce count for requests without body.
+// Requests with body are passed to the plain digest transport, which buffers
+// the body for the authorized retry.
+type digestSessionTransport struct {
+	auth *digest.Transport // credentials, nonce counter and base transport
+
+	mu        sync.Mutex
+	challenge *digest.Challenge
+}
+
+// RoundTrip implements http.RoundTripper, authorizing preemptively if a challenge is cached.
+func (t *digestSessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		return t.auth.RoundTrip(req)
+	}
+
+	t.mu.Lock()
+	chal := t.challenge
+	t.mu.Unlock()
+
+	var resp *http.Response
+	var err error
+	if chal != nil {
+		resp, err = t.authorize(req, chal)
+	} else {
+		resp, err = t.auth.Transport.RoundTrip(req)
+	}
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// no or expired challenge, answer the new one
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	wwwAuth := resp.Header.Get("WWW-Authenticate")
+	if wwwAuth == "" {
+		// non-standard header some servers use to avoid browser popups
+		wwwAuth = resp.Header.Get("X-WWW-Authenticate")
+	}
+	chal, err = digest.NewChallenge(wwwAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.challenge = chal
+	t.mu.Unlock()
+
+	return t.authorize(req, chal)
+}
+
+// reset drops the cached challenge, forcing a new handshake
+func (t *digestSessionTransport) reset() {
+	t.mu.Lock()
+	t.challenge = nil
+	t.mu.Unlock()
+}
+
+// authorize sends the request with an Authorization header answering the challenge
+func (t *digestSessionTransport) authorize(req *http.Request, chal *digest.Challenge) (*http.Response, error) {
+	cnonce, err := t.auth.Cnoncer()
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := t.auth.NewCredentials(req.Method, req.URL.RequestURI(), "", cnonce, chal).Authorization()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", auth)
+
+	return t.auth.Transport.RoundTrip(req)
+}
+
+// credentialsTransport adds digest authentication using credentials from a provider.
+// The provider is asked on each request, so rotated credentials take effect
+// without re-creating the client.
+type credentialsTransport struct {
+	provider CredentialsProvider
+	base     http.RoundTripper
+
+	mu       sync.Mutex
+	user     string
+	password string
+	digest   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper, recreating the digest transport when credentials change.
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	user, password, err := t.provider.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	t.mu.Lock()
+	if t.digest == nil || user != t.user || password != t.password {
+		t.user, t.password = user, password
+		t.digest = NewDigestTransport(user, password, t.base)
+	}
+	rt := t.digest
+	t.mu.Unlock()
+
+	return rt.RoundTrip(req)
+}
+
+// reset drops the digest session, forcing a new handshake
+func (t *credentialsTransport) reset() {
+	t.mu.Lock()
+	t.digest = nil
+	t.mu.Unlock()
 }
@@ -0,0 +1,101 @@
+package emhcasa
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDigestSessionCaching tests reuse of the digest challenge across requests
+func TestDigestSessionCaching(t *testing.T) {
+	tests := []struct {
+		name      string
+		nonceUses int // requests accepted per nonce, 0 = unlimited
+		requests  int
+		wantHits  int
+	}{
+		{
+			name:      "challenge reused",
+			nonceUses: 0,
+			requests:  3,
+			wantHits:  4, // 1 challenge + 3 authorized
+		},
+		{
+			name:      "expired nonce renewed",
+			nonceUses: 2,
+			requests:  3,
+			wantHits:  5, // 1 challenge + 2 authorized + 1 rejected + 1 authorized with new nonce
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits, nonce, uses int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				auth := r.Header.Get("Authorization")
+				expired := tt.nonceUses > 0 && uses >= tt.nonceUses
+				if auth == "" || !strings.Contains(auth, fmt.Sprintf(`nonce="n%d"`, nonce)) || expired {
+					if expired {
+						nonce++
+						uses = 0
+					}
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="smgw", nonce="n%d", qop="auth"`, nonce))
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				uses++
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			client := &http.Client{Transport: NewDigestTransport("admin", "pass", http.DefaultTransport)}
+
+			for i := 0; i < tt.requests; i++ {
+				resp, err := client.Get(srv.URL)
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("request %d: status = %d, want 200", i, resp.StatusCode)
+				}
+			}
+
+			if hits != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+// TestDigestBody tests that requests with body are sent complete after the challenge
+func TestDigestBody(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `Digest realm="smgw", nonce="n0", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewDigestTransport("admin", "pass", http.DefaultTransport)}
+
+	// a reader without GetBody can't be rewound by net/http
+	resp, err := client.Post(srv.URL, "application/json", io.MultiReader(strings.NewReader(`{"a":1}`)))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || len(bodies) != 1 || bodies[0] != `{"a":1}` {
+		t.Errorf("Post() = %d, bodies %q, want body sent", resp.StatusCode, bodies)
+	}
+}
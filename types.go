@@ -24,21 +24,257 @@
 //	power := values["16.7.0"] // OBIS 16.7.0 = current power in W
 package emhcasa
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // DerivedContract represents a metering contract from the CASA gateway.
 type DerivedContract struct {
+	ID            string   `json:"-"` // contract ID as listed by the gateway
 	TafType       string   `json:"taf_type"`
+	State         string   `json:"state"` // contract state, e.g. "active"; empty if the firmware does not report it
 	SensorDomains []string `json:"sensor_domains"`
 }
 
+// Unit is a DLMS/COSEM unit code as reported by the gateway.
+type Unit int
+
+// DLMS/COSEM unit codes handled by the client.
+const (
+	UnitCelsius        Unit = 9
+	UnitCubicMetre     Unit = 13
+	UnitWatt           Unit = 27
+	UnitVoltAmpere     Unit = 28
+	UnitVar            Unit = 29
+	UnitWattHour       Unit = 30
+	UnitVoltAmpereHour Unit = 31
+	UnitVarHour        Unit = 32
+	UnitAmpere         Unit = 33
+	UnitVolt           Unit = 35
+	UnitHertz          Unit = 44
+	UnitPercent        Unit = 56
+	UnitDimensionless  Unit = 255 // no unit, e.g. power factor
+)
+
+// String returns the unit symbol.
+func (u Unit) String() string {
+	switch u {
+	case UnitCelsius:
+		return "°C"
+	case UnitCubicMetre:
+		return "m³"
+	case UnitWatt:
+		return "W"
+	case UnitVoltAmpere:
+		return "VA"
+	case UnitVar:
+		return "var"
+	case UnitWattHour:
+		return "Wh"
+	case UnitVoltAmpereHour:
+		return "VAh"
+	case UnitVarHour:
+		return "varh"
+	case UnitAmpere:
+		return "A"
+	case UnitVolt:
+		return "V"
+	case UnitHertz:
+		return "Hz"
+	case UnitPercent:
+		return "%"
+	case UnitDimensionless:
+		return ""
+	}
+	return fmt.Sprintf("Unit(%d)", int(u))
+}
+
+// MarshalJSON encodes known units as symbol, unknown units as number.
+func (u Unit) MarshalJSON() ([]byte, error) {
+	if _, ok := unitSymbols()[u.String()]; !ok {
+		return json.Marshal(int(u))
+	}
+	return json.Marshal(u.String())
+}
+
+// UnmarshalJSON decodes a unit symbol or a DLMS unit code as sent by the gateway.
+func (u *Unit) UnmarshalJSON(b []byte) error {
+	var code int
+	if err := json.Unmarshal(b, &code); err == nil {
+		*u = Unit(code)
+		return nil
+	}
+
+	var symbol string
+	if err := json.Unmarshal(b, &symbol); err != nil {
+		return fmt.Errorf("invalid unit: %s", b)
+	}
+
+	unit, ok := unitSymbols()[symbol]
+	if !ok {
+		return fmt.Errorf("unknown unit: %s", symbol)
+	}
+
+	*u = unit
+	return nil
+}
+
+// unitSymbols maps the symbols of all known units to their codes
+func unitSymbols() map[string]Unit {
+	res := make(map[string]Unit)
+	for _, u := range []Unit{
+		UnitCelsius, UnitCubicMetre, UnitWatt, UnitVoltAmpere, UnitVar, UnitWattHour,
+		UnitVoltAmpereHour, UnitVarHour, UnitAmpere, UnitVolt, UnitHertz, UnitPercent, UnitDimensionless,
+	} {
+		res[u.String()] = u
+	}
+	return res
+}
+
 // MeterValue represents a single meter reading value from the gateway.
 type MeterValue struct {
-	Value       string `json:"value"`
-	Unit        int    `json:"unit"`         // 27 = W, 30 = Wh, 33 = A, 35 = V, 44 = Hz
-	Scaler      int    `json:"scaler"`       // power-of-10 multiplier
-	LogicalName string `json:"logical_name"` // CASA logical name in hex format
+	Value       string          `json:"value"`
+	Unit        Unit            `json:"unit"`                   // DLMS unit code, e.g. 27 = W
+	Scaler      int             `json:"scaler"`                 // power-of-10 multiplier
+	LogicalName string          `json:"logical_name"`           // CASA logical name in hex format
+	Status      json.RawMessage `json:"status,omitempty"`       // per-value status flags, if reported
+	CaptureTime string          `json:"capture_time,omitempty"` // RFC 3339 capture time, if reported
 }
 
 // MeterReading represents the complete meter reading response from the gateway.
 type MeterReading struct {
 	Values []MeterValue `json:"values"`
 }
+
+// Reading is a single converted meter value as returned by Client.GetReadings.
+// It marshals to JSON with units and quality as strings, plus the unit of Value as "value_unit".
+type Reading struct {
+	OBIS        string  `json:"obis"`         // key as used by GetMeterValues, e.g. "1.8.0"
+	LogicalName string  `json:"logical_name"` // logical name as reported by the gateway, e.g. "0100010800FF.255"
+	Value       float64 `json:"value"`        // scaled value, energies converted to kWh/kVAh/kvarh
+	ValueMilli  int64   `json:"value_milli"`  // Value in thousandths (e.g. Wh for kWh) computed exactly from RawValue
+	Unit        Unit    `json:"unit"`         // unit code as reported by the gateway
+	RawValue    string  `json:"raw_value"`    // unscaled value exactly as reported by the gateway
+	Scaler      int     `json:"scaler"`       // power-of-10 scaler as reported by the gateway
+
+	StatusRaw string  `json:"status_raw,omitempty"` // status flags as reported by the gateway, empty if none
+	Quality   Quality `json:"quality"`              // validity derived from StatusRaw or set by wrappers
+
+	Timestamp  time.Time `json:"timestamp,omitzero"`   // capture time reported by the gateway, ReceivedAt if none
+	ReceivedAt time.Time `json:"received_at,omitzero"` // time the value was retrieved from the gateway
+}
+
+// ValueUnit returns the unit symbol of Value, e.g. "kWh" for values reported in Wh.
+func (r Reading) ValueUnit() string {
+	switch r.Unit {
+	case UnitWattHour, UnitVoltAmpereHour, UnitVarHour:
+		return "k" + r.Unit.String()
+	}
+	return r.Unit.String()
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Reading) MarshalJSON() ([]byte, error) {
+	type reading Reading // without methods, avoids recursion
+	return json.Marshal(struct {
+		reading
+		ValueUnit string `json:"value_unit"`
+	}{reading(r), r.ValueUnit()})
+}
+
+// Values returns the readings keyed by OBIS code as returned by GetMeterValues,
+// e.g. for NewGridSnapshot or Phases. Later readings of a code win.
+func Values(readings []Reading) map[string]float64 {
+	values := make(map[string]float64, len(readings))
+	for _, r := range readings {
+		values[r.OBIS] = r.Value
+	}
+	return values
+}
+
+// Quality is the validity of a reading, ordered by severity.
+type Quality int
+
+const (
+	QualityGood         Quality = iota // no status reported or status zero
+	QualityCounterReset                // energy counter decreased, e.g. after a meter swap
+	QualityInvalid                     // gateway reported a non-zero status
+)
+
+// String returns the quality name.
+func (q Quality) String() string {
+	switch q {
+	case QualityGood:
+		return "good"
+	case QualityCounterReset:
+		return "counter_reset"
+	case QualityInvalid:
+		return "invalid"
+	}
+	return fmt.Sprintf("Quality(%d)", int(q))
+}
+
+// MarshalJSON encodes the quality name.
+func (q Quality) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.String())
+}
+
+// UnmarshalJSON decodes a quality name.
+func (q *Quality) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return fmt.Errorf("invalid quality: %s", b)
+	}
+
+	switch name {
+	case "good":
+		*q = QualityGood
+	case "counter_reset":
+		*q = QualityCounterReset
+	case "invalid":
+		*q = QualityInvalid
+	default:
+		return fmt.Errorf("unknown quality: %s", name)
+	}
+
+	return nil
+}
+
+// MeterUpdate is a polling result delivered by Client.Subscribe.
+type MeterUpdate struct {
+	Time   time.Time          // time the values were retrieved
+	Values map[string]float64 // OBIS code to value, as returned by GetMeterValues
+	Err    error              // non-nil if the poll failed
+}
+
+// Gateway is a source of meter readings. It is implemented by Client and by
+// wrappers adding behavior on top of it.
+type Gateway interface {
+	GetReadings() ([]Reading, error)
+}
+
+// StatusError is returned for gateway responses with an unexpected HTTP status code.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Code)
+}
+
+// CredentialsProvider supplies digest authentication credentials, e.g. from a
+// secret store. It is called for every request, so implementations should cache
+// credentials as needed.
+type CredentialsProvider interface {
+	Credentials() (user, password string, err error)
+}
+
+// CredentialsFunc adapts a function to the CredentialsProvider interface.
+type CredentialsFunc func() (user, password string, err error)
+
+// Credentials implements CredentialsProvider.
+func (f CredentialsFunc) Credentials() (string, string, error) {
+	return f()
+}
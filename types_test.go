@@ -0,0 +1,103 @@
+package emhcasa
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestUnitString tests unit symbols of DLMS unit codes
+func TestUnitString(t *testing.T) {
+	tests := []struct {
+		unit Unit
+		want string
+	}{
+		{UnitWatt, "W"},
+		{UnitVar, "var"},
+		{UnitVoltAmpereHour, "VAh"},
+		{UnitVarHour, "varh"},
+		{UnitCelsius, "°C"},
+		{UnitCubicMetre, "m³"},
+		{UnitPercent, "%"},
+		{UnitDimensionless, ""},
+		{Unit(7), "Unit(7)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.unit.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReadingJSON tests JSON encoding and round-tripping of readings
+func TestReadingJSON(t *testing.T) {
+	r := Reading{
+		OBIS:        "1.8.0",
+		LogicalName: "0100010800FF.255",
+		Value:       300.5,
+		ValueMilli:  300500,
+		Unit:        UnitWattHour,
+		RawValue:    "3005",
+		Scaler:      2,
+		StatusRaw:   "0x08",
+		Quality:     QualityInvalid,
+		Timestamp:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		ReceivedAt:  time.Date(2026, 3, 1, 12, 0, 5, 0, time.UTC),
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := `{"obis":"1.8.0","logical_name":"0100010800FF.255","value":300.5,"value_milli":300500,"unit":"Wh",` +
+		`"raw_value":"3005","scaler":2,"status_raw":"0x08","quality":"invalid",` +
+		`"timestamp":"2026-03-01T12:00:00Z","received_at":"2026-03-01T12:00:05Z","value_unit":"kWh"}`
+	if string(b) != want {
+		t.Errorf("Marshal() = %s, want %s", b, want)
+	}
+
+	var got Reading
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got != r {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, r)
+	}
+}
+
+// TestUnitJSON tests decoding of gateway unit codes and symbols
+func TestUnitJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Unit
+		wantErr bool
+	}{
+		{input: `27`, want: UnitWatt},
+		{input: `"m³"`, want: UnitCubicMetre},
+		{input: `""`, want: UnitDimensionless},
+		{input: `7`, want: Unit(7)},
+		{input: `"furlong"`, wantErr: true},
+		{input: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got Unit
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if b, _ := json.Marshal(Unit(7)); string(b) != "7" {
+		t.Errorf("Marshal(Unit(7)) = %s, want 7", b)
+	}
+}
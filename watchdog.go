@@ -0,0 +1,63 @@
+package emhcasa
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Watchdog wraps a Client and recovers it after prolonged failure. If no request
+// succeeded for the configured timeout, a failing request triggers mDNS discovery;
+// the client is switched to the discovered address with fresh connections and
+// digest session, and the request is retried once. Consumers keep using the
+// Watchdog without restarting.
+type Watchdog struct {
+	client   *Client
+	timeout  time.Duration
+	discover func() (string, error)
+
+	mu     sync.Mutex
+	lastOK time.Time // last successful request or recovery attempt
+}
+
+// NewWatchdog wraps c, recovering it after timeout without successful readings.
+func NewWatchdog(c *Client, timeout time.Duration) *Watchdog {
+	return &Watchdog{
+		client:   c,
+		timeout:  timeout,
+		discover: DiscoverGatewayURI,
+		lastOK:   time.Now(),
+	}
+}
+
+// GetReadings implements Gateway.
+func (w *Watchdog) GetReadings() ([]Reading, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	readings, err := w.client.GetReadings()
+	if err == nil {
+		w.lastOK = time.Now()
+		return readings, nil
+	}
+
+	if time.Since(w.lastOK) < w.timeout {
+		return nil, err
+	}
+
+	// restart the timer, so discovery runs at most once per timeout
+	w.lastOK = time.Now()
+
+	uri, derr := w.discover()
+	if derr != nil {
+		return nil, fmt.Errorf("%w (re-discovery failed: %v)", err, derr)
+	}
+
+	w.client.reconnect(uri)
+
+	if readings, err = w.client.GetReadings(); err == nil {
+		w.lastOK = time.Now()
+	}
+
+	return readings, err
+}
@@ -0,0 +1,52 @@
+package emhcasa
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestWatchdog tests recovery by re-discovery after prolonged failure
+func TestWatchdog(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"values":[{"value":"2500","unit":27,"scaler":0,"logical_name":"0100100700FF.255"}]}`)
+	}))
+	defer srv.Close()
+
+	old := httptest.NewTLSServer(http.NotFoundHandler())
+	old.Close()
+
+	c, err := NewClient(old.URL, "admin", "pass", "123")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	var discoveries int
+	w := NewWatchdog(c, 20*time.Millisecond)
+	w.discover = func() (string, error) {
+		discoveries++
+		return srv.URL, nil
+	}
+
+	if _, err := w.GetReadings(); err == nil {
+		t.Fatal("GetReadings() expected error before timeout")
+	}
+	if discoveries != 0 {
+		t.Errorf("discovery ran %d times before timeout, want 0", discoveries)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	readings, err := w.GetReadings()
+	if err != nil {
+		t.Fatalf("GetReadings() error = %v", err)
+	}
+	if len(readings) != 1 || readings[0].Value != 2500 || discoveries != 1 {
+		t.Errorf("GetReadings() = %+v after %d discoveries", readings, discoveries)
+	}
+	if uri := c.gatewayURI(); uri != srv.URL {
+		t.Errorf("client uri = %v, want %v", uri, srv.URL)
+	}
+}